@@ -3,4 +3,20 @@ package common
 const (
 	// OpenAI-compatible API base URL
 	DefaultBaseURL = "https://api.openai.com/v1/"
+
+	// Anthropic messages API base URL, used by provider "anthropic"
+	DefaultAnthropicBaseURL = "https://api.anthropic.com/v1/"
+
+	// Local Ollama server, used by provider "ollama"
+	DefaultOllamaBaseURL = "http://localhost:11434"
+)
+
+// Exit codes, so scripts can tell failures apart
+const (
+	ExitSuccess   = 0 // Success
+	ExitGeneric   = 1 // Any other error
+	ExitConfig    = 2 // Configuration could not be loaded or is invalid
+	ExitAuth      = 3 // The provider rejected the API key (401/403)
+	ExitNetwork   = 4 // Network failure or timeout
+	ExitNoContent = 5 // The model returned no content or the answer was filtered
 )
@@ -0,0 +1,90 @@
+package config_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"ask_terminal/config"
+	"ask_terminal/utils"
+)
+
+// loadTestConfig writes body to a config file in a temporary directory and loads it.
+// The key refers to an environment variable so the file is never rewritten.
+func loadTestConfig(t *testing.T, body string) *config.Config {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_DATA_HOME", dir)
+	t.Setenv("ASKTA_TEST_KEY", "sk-test")
+	utils.SetLoggingEnabled(false)
+
+	path := filepath.Join(dir, "config.yaml")
+	content := "base_url: \"http://127.0.0.1:1/v1/\"\napi_key: \"${ASKTA_TEST_KEY}\"\nmodel_name: \"gpt-4o-mini\"\nprovider: \"openai-compatible\"\nprivate_mode: true\n" + body
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	conf, err := config.LoadConfig(path, "")
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	return conf
+}
+
+func TestLoadConfigTemperature(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		unset bool    // conf.Temperature is nil
+		want  float64 // temperature used and sent
+	}{
+		{name: "absent", body: "# temperature: 0.9\n", unset: true, want: config.DefaultTemperature},
+		{name: "explicit zero", body: "temperature: 0\n", want: 0},
+		{name: "non-zero", body: "temperature: 0.3\n", want: 0.3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := loadTestConfig(t, tt.body)
+
+			if unset := conf.Temperature == nil; unset != tt.unset {
+				t.Fatalf("Temperature unset = %v, want %v", unset, tt.unset)
+			}
+			if got := conf.TemperatureValue(); got != tt.want {
+				t.Errorf("TemperatureValue() = %v, want %v", got, tt.want)
+			}
+
+			request := utils.BuildPrompt("hello", conf, "chat")
+			if request.Temperature == nil || *request.Temperature != tt.want {
+				t.Fatalf("BuildPrompt() temperature = %v, want %v", request.Temperature, tt.want)
+			}
+
+			// An explicit 0 must survive omitempty
+			data, err := json.Marshal(request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var sent map[string]any
+			if err := json.Unmarshal(data, &sent); err != nil {
+				t.Fatal(err)
+			}
+			if value, ok := sent["temperature"]; !ok || value != tt.want {
+				t.Errorf("request temperature = %v (sent %v), want %v", value, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigKeyringProfileInheritsKey(t *testing.T) {
+	// Profiles without an api_key must not need a reachable keyring
+	conf := loadTestConfig(t, "key_storage: keyring\nprofiles:\n  local:\n    model_name: \"llama3\"\n")
+
+	if conf.APIKey != "sk-test" {
+		t.Fatalf("APIKey = %q, want the top-level key", conf.APIKey)
+	}
+	if key := conf.Profiles["local"].APIKey; key != "" {
+		t.Errorf("profile APIKey = %q, want it left empty to inherit", key)
+	}
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"os"
+	"regexp"
+)
+
+// envReference matches $VAR, ${VAR} and the $$ escape for a literal dollar sign
+var envReference = regexp.MustCompile(`\$\$|\$\{[A-Za-z_][A-Za-z0-9_]*\}|\$[A-Za-z_][A-Za-z0-9_]*`)
+
+// hasEnvReference reports whether a value refers to an environment variable
+func hasEnvReference(value string) bool {
+	for _, match := range envReference.FindAllString(value, -1) {
+		if match != "$$" {
+			return true
+		}
+	}
+	return false
+}
+
+// expandEnv replaces $VAR and ${VAR} with the variable's value, empty when it is
+// unset as in the shell, and $$ with $. Other dollar signs, such as "$5", are kept.
+func expandEnv(value string) string {
+	return envReference.ReplaceAllStringFunc(value, func(match string) string {
+		if match == "$$" {
+			return "$"
+		}
+		name := match[1:]
+		if name[0] == '{' {
+			name = name[1 : len(name)-1]
+		}
+		return os.Getenv(name)
+	})
+}
+
+// expandEnvFields expands environment variables in the settings that commonly
+// hold secrets, endpoints and prompts, including those of every profile
+func (c *Config) expandEnvFields() {
+	fields := []*string{
+		&c.BaseURL, &c.APIKey, &c.ModelName, &c.Provider, &c.Proxy,
+		&c.TerminalModel, &c.ChatModel, &c.EmbeddingModel,
+		&c.SysPrompt, &c.ScriptPrompt, &c.AppendInstruction,
+		&c.AuthHeaderName, &c.AuthQueryParam, &c.Shell,
+	}
+	for _, field := range fields {
+		*field = expandEnv(*field)
+	}
+
+	for name, profile := range c.Profiles {
+		for _, field := range []*string{
+			&profile.BaseURL, &profile.APIKey, &profile.ModelName, &profile.Provider,
+			&profile.SysPrompt, &profile.AppendInstruction, &profile.Mode,
+		} {
+			*field = expandEnv(*field)
+		}
+		c.Profiles[name] = profile
+	}
+}
@@ -0,0 +1,159 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// configField finds the Config field stored under a top-level YAML key
+func configField(key string) (reflect.StructField, bool) {
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if yamlName(field) == key {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// yamlName returns the key a field is stored under, "" for fields not in the file
+func yamlName(field reflect.StructField) string {
+	if field.PkgPath != "" {
+		return ""
+	}
+	name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// ConfigKeys lists the top-level settings of the config file in alphabetical order
+func ConfigKeys() []string {
+	t := reflect.TypeOf(Config{})
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		if name := yamlName(t.Field(i)); name != "" {
+			keys = append(keys, name)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// unknownKeyError reports a key that isn't a setting, suggesting those it starts like
+func unknownKeyError(key string) error {
+	var similar []string
+	prefix := strings.SplitN(key, "_", 2)[0]
+	for _, known := range ConfigKeys() {
+		if strings.HasPrefix(known, prefix) {
+			similar = append(similar, known)
+		}
+	}
+	if len(similar) > 0 {
+		return fmt.Errorf("unknown config key %q (did you mean %s?)", key, strings.Join(similar, ", "))
+	}
+	return fmt.Errorf("unknown config key %q", key)
+}
+
+// GetConfigValue returns a setting as it is in the config file: scalars as text,
+// lists and maps as YAML. API keys are redacted, references to environment
+// variables are shown as written.
+func GetConfigValue(configPath string, key string) (string, error) {
+	field, ok := configField(key)
+	if !ok {
+		return "", unknownKeyError(key)
+	}
+	conf, err := ReadConfigFile(configPath)
+	if err != nil {
+		return "", err
+	}
+
+	value := reflect.ValueOf(conf).Elem().FieldByIndex(field.Index)
+	if key == "api_key" && !hasEnvReference(conf.APIKey) {
+		return RedactKey(conf.APIKey), nil
+	}
+	if value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return "", nil
+		}
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Slice, reflect.Map:
+		if value.Len() == 0 {
+			return "", nil
+		}
+		data, err := yaml.Marshal(value.Interface())
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\n"), nil
+	default:
+		return fmt.Sprint(value.Interface()), nil
+	}
+}
+
+// SetConfigValue validates value against the type of the setting and writes it
+// to the config file, keeping the rest of the file (including comments)
+// untouched. api_key is encrypted as on the first run, unless it refers to an
+// environment variable. Lists and maps have to be edited in the file.
+func SetConfigValue(configPath string, key string, value string) error {
+	field, ok := configField(key)
+	if !ok {
+		return unknownKeyError(key)
+	}
+	if key == "api_key" && !hasEnvReference(value) {
+		return SaveAPIKey(configPath, value)
+	}
+
+	kind := field.Type.Kind()
+	if kind == reflect.Ptr {
+		kind = field.Type.Elem().Kind()
+	}
+
+	var literal string
+	switch kind {
+	case reflect.String:
+		literal = strconv.Quote(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%s must be true or false", key)
+		}
+		literal = strconv.FormatBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%s must be a whole number", key)
+		}
+		literal = strconv.Itoa(n)
+	case reflect.Uint:
+		n, err := strconv.ParseUint(value, 10, 0)
+		if err != nil {
+			return fmt.Errorf("%s must be a whole number of 0 or more", key)
+		}
+		literal = strconv.FormatUint(n, 10)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%s must be a number", key)
+		}
+		literal = strconv.FormatFloat(f, 'f', -1, 64)
+	default:
+		return fmt.Errorf("%s is a list or map; edit it in the config file", key)
+	}
+
+	configPath, err := ResolveConfigPath(configPath)
+	if err != nil {
+		return err
+	}
+	return setConfigLine(configPath, key, literal)
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/template"
+)
+
+// TemplateModes are the modes whose system prompt can be replaced under templates:
+var TemplateModes = []string{"terminal", "chat"}
+
+// PromptTemplateData is what a templates: entry can use. Fields left out of the
+// request, such as CWD and DirTree in private mode, are empty.
+type PromptTemplateData struct {
+	OS            string // Operating system and architecture, e.g. "linux - amd64"
+	Shell         string // Shell commands are written for
+	CWD           string // Working directory
+	DirTree       string // Directory structure of the working directory
+	UserSysPrompt string // The sys_prompt setting
+	Format        string // Built-in answer format instructions (the JSON suggestion format in terminal mode)
+}
+
+// PromptTemplate returns the parsed template for mode, nil when templates: has none
+func (c *Config) PromptTemplate(mode string) (*template.Template, error) {
+	text, ok := c.Templates[mode]
+	if !ok || text == "" {
+		return nil, nil
+	}
+	return template.New(mode).Option("missingkey=error").Parse(text)
+}
+
+// validateTemplates parses every template and renders it with empty data, so
+// unknown modes, syntax errors and unknown fields are reported with their line
+// when the config is loaded rather than on the first request
+func validateTemplates(c *Config) error {
+	modes := make([]string, 0, len(c.Templates))
+	for mode := range c.Templates {
+		modes = append(modes, mode)
+	}
+	sort.Strings(modes)
+
+	for _, mode := range modes {
+		if !isTemplateMode(mode) {
+			return fmt.Errorf("unknown templates entry %q (available: terminal, chat)", mode)
+		}
+		tmpl, err := c.PromptTemplate(mode)
+		if err != nil {
+			return fmt.Errorf("invalid templates.%s: %w", mode, err)
+		}
+		if tmpl == nil {
+			continue
+		}
+		if err := tmpl.Execute(io.Discard, PromptTemplateData{}); err != nil {
+			return fmt.Errorf("invalid templates.%s: %w", mode, err)
+		}
+	}
+	return nil
+}
+
+// isTemplateMode reports whether templates: may have an entry for mode
+func isTemplateMode(mode string) bool {
+	for _, m := range TemplateModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
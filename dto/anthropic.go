@@ -0,0 +1,73 @@
+package dto
+
+// AnthropicRequest is a request to Anthropic's /v1/messages endpoint
+type AnthropicRequest struct {
+	Model         string             `json:"model"`
+	System        any                `json:"system,omitempty"` // A string, or []AnthropicContent for cache_control
+	Messages      []AnthropicMessage `json:"messages"`
+	MaxTokens     uint               `json:"max_tokens"`
+	Stream        bool               `json:"stream,omitempty"`
+	Temperature   *float64           `json:"temperature,omitempty"`
+	TopP          *float64           `json:"top_p,omitempty"`
+	StopSequences []string           `json:"stop_sequences,omitempty"`
+}
+
+// AnthropicMessage is one turn of a conversation
+type AnthropicMessage struct {
+	Role    string             `json:"role"`
+	Content []AnthropicContent `json:"content"`
+}
+
+// AnthropicContent is a content block in a message or the system prompt
+type AnthropicContent struct {
+	Type         string                `json:"type"`
+	Text         string                `json:"text,omitempty"`
+	Thinking     string                `json:"thinking,omitempty"`
+	Source       *AnthropicImageSource `json:"source,omitempty"`
+	CacheControl *CacheControl         `json:"cache_control,omitempty"`
+}
+
+// AnthropicImageSource is the image of an image block, inline as base64 or by URL
+type AnthropicImageSource struct {
+	Type      string `json:"type"` // "base64" or "url"
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+// AnthropicResponse is the answer of the messages endpoint
+type AnthropicResponse struct {
+	Id         string             `json:"id"`
+	Type       string             `json:"type"`
+	Role       string             `json:"role"`
+	Model      string             `json:"model"`
+	Content    []AnthropicContent `json:"content"`
+	StopReason string             `json:"stop_reason"`
+	Usage      AnthropicUsage     `json:"usage"`
+}
+
+// AnthropicUsage reports the tokens used by a request
+type AnthropicUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens,omitempty"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens,omitempty"`
+}
+
+// AnthropicStreamEvent is one server-sent event of a streamed answer
+type AnthropicStreamEvent struct {
+	Type    string             `json:"type"`
+	Message *AnthropicResponse `json:"message,omitempty"` // message_start
+	Index   int                `json:"index"`
+	Delta   *AnthropicDelta    `json:"delta,omitempty"` // content_block_delta, message_delta
+	Usage   *AnthropicUsage    `json:"usage,omitempty"` // message_delta
+	Error   *OpenAIError       `json:"error,omitempty"` // error
+}
+
+// AnthropicDelta is the change carried by a delta event
+type AnthropicDelta struct {
+	Type       string `json:"type"` // text_delta or thinking_delta in content_block_delta
+	Text       string `json:"text,omitempty"`
+	Thinking   string `json:"thinking,omitempty"`
+	StopReason string `json:"stop_reason,omitempty"`
+}
@@ -0,0 +1,49 @@
+package dto
+
+// OllamaRequest is a request to Ollama's /api/chat endpoint
+type OllamaRequest struct {
+	Model    string          `json:"model"`
+	Messages []OllamaMessage `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Format   string          `json:"format,omitempty"` // "json" constrains the answer to JSON
+	Options  *OllamaOptions  `json:"options,omitempty"`
+}
+
+// OllamaMessage is one chat message; images are base64 encoded
+type OllamaMessage struct {
+	Role     string   `json:"role"`
+	Content  string   `json:"content"`
+	Thinking string   `json:"thinking,omitempty"`
+	Images   []string `json:"images,omitempty"`
+}
+
+// OllamaOptions holds the model parameters Ollama accepts
+type OllamaOptions struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	NumPredict  uint     `json:"num_predict,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+}
+
+// OllamaResponse is an answer, or one line of a streamed answer
+type OllamaResponse struct {
+	Model           string        `json:"model"`
+	CreatedAt       string        `json:"created_at"`
+	Message         OllamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	DoneReason      string        `json:"done_reason,omitempty"`
+	PromptEvalCount int           `json:"prompt_eval_count,omitempty"`
+	EvalCount       int           `json:"eval_count,omitempty"`
+	Error           string        `json:"error,omitempty"`
+}
+
+// OllamaEmbedRequest is a request to Ollama's /api/embed endpoint
+type OllamaEmbedRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// OllamaEmbedResponse holds one embedding per input
+type OllamaEmbedResponse struct {
+	Embeddings [][]float32 `json:"embeddings"`
+}
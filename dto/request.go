@@ -19,6 +19,15 @@ type GeneralOpenAIRequest struct {
 	Stop             []string        `json:"stop,omitempty"`
 	Input            any             `json:"input,omitempty"`
 	ResponseFormat   *ResponseFormat `json:"response_format,omitempty"`
+	PromptCacheKey   string          `json:"prompt_cache_key,omitempty"`
+	ReasoningEffort  string          `json:"reasoning_effort,omitempty"`
+	Tools            []Tool          `json:"tools,omitempty"`
+}
+
+// EmbeddingRequest is a request to the embeddings endpoint
+type EmbeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
 }
 
 // ResponseFormat specifies the format for response
@@ -26,6 +35,20 @@ type ResponseFormat struct {
 	Type string `json:"type"`
 }
 
+// Tool is a function offered to the model
+type Tool struct {
+	Type     string             `json:"type"`
+	Function FunctionDefinition `json:"function"`
+}
+
+// FunctionDefinition describes a function the model may call; Parameters is its
+// JSON schema
+type FunctionDefinition struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Parameters  any    `json:"parameters"`
+}
+
 // ToolCallRequest represents a tool call in a message
 type ToolCallRequest struct {
 	ID       string          `json:"id"`
@@ -39,6 +62,16 @@ type FunctionRequest struct {
 	Arguments json.RawMessage `json:"arguments"`
 }
 
+// ParseArguments decodes the call arguments into v. OpenAI sends them as a string
+// holding JSON, some compatible servers as a JSON object; both are accepted.
+func (f FunctionRequest) ParseArguments(v any) error {
+	var encoded string
+	if err := json.Unmarshal(f.Arguments, &encoded); err == nil {
+		return json.Unmarshal([]byte(encoded), v)
+	}
+	return json.Unmarshal(f.Arguments, v)
+}
+
 // MessageImageUrl represents an image URL in a message
 type MessageImageUrl struct {
 	Url    string `json:"url"`
@@ -51,6 +84,11 @@ type MessageInputAudio struct {
 	Format string `json:"format"`
 }
 
+// CacheControl marks a content block as a prompt caching breakpoint
+type CacheControl struct {
+	Type string `json:"type"`
+}
+
 // MediaContent represents media content in a message
 type MediaContent struct {
 	Type       string             `json:"type"`
@@ -87,10 +125,16 @@ type Message struct {
 	Reasoning           string          `json:"reasoning,omitempty"`
 	ToolCalls           json.RawMessage `json:"tool_calls,omitempty"`
 	ToolCallId          string          `json:"tool_call_id,omitempty"`
+	Refusal             string          `json:"refusal,omitempty"`
+	Annotations         []Annotation    `json:"annotations,omitempty"`
 	parsedContent       []MediaContent
 	parsedStringContent *string
 }
 
+const (
+	CacheControlEphemeral = "ephemeral"
+)
+
 const (
 	ContentTypeText       = "text"
 	ContentTypeImageURL   = "image_url"
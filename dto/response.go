@@ -14,6 +14,10 @@ type TextResponse struct {
 	Usage   `json:"usage"`
 }
 
+const (
+	FinishReasonContentFilter = "content_filter"
+)
+
 type OpenAITextResponseChoice struct {
 	Index        int `json:"index"`
 	Message      `json:"message"`
@@ -28,6 +32,51 @@ type OpenAITextResponse struct {
 	Choices []OpenAITextResponseChoice `json:"choices"`
 	Error   *OpenAIError               `json:"error,omitempty"`
 	Usage   `json:"usage"`
+	// Citations lists source URLs, as returned by search-augmented providers such as Perplexity
+	Citations []string `json:"citations,omitempty"`
+}
+
+// Annotation marks part of an answer, e.g. a url_citation from a web-search model
+type Annotation struct {
+	Type        string       `json:"type"`
+	URLCitation *URLCitation `json:"url_citation,omitempty"`
+}
+
+type URLCitation struct {
+	StartIndex int    `json:"start_index"`
+	EndIndex   int    `json:"end_index"`
+	URL        string `json:"url"`
+	Title      string `json:"title,omitempty"`
+}
+
+// Citation is a source referenced by an answer
+type Citation struct {
+	Title string
+	URL   string
+}
+
+// CollectCitations merges url_citation annotations and plain citation URLs,
+// dropping duplicate URLs and keeping first-seen order
+func CollectCitations(annotations []Annotation, urls []string) []Citation {
+	var citations []Citation
+	seen := make(map[string]bool)
+	add := func(title, url string) {
+		if url == "" || seen[url] {
+			return
+		}
+		seen[url] = true
+		citations = append(citations, Citation{Title: title, URL: url})
+	}
+
+	for _, annotation := range annotations {
+		if annotation.URLCitation != nil {
+			add(annotation.URLCitation.Title, annotation.URLCitation.URL)
+		}
+	}
+	for _, url := range urls {
+		add("", url)
+	}
+	return citations
 }
 
 type OpenAIEmbeddingResponseItem struct {
@@ -55,7 +104,9 @@ type ChatCompletionsStreamResponseChoiceDelta struct {
 	ReasoningContent *string            `json:"reasoning_content,omitempty"`
 	Reasoning        *string            `json:"reasoning,omitempty"`
 	Role             string             `json:"role,omitempty"`
+	Refusal          *string            `json:"refusal,omitempty"`
 	ToolCalls        []ToolCallResponse `json:"tool_calls,omitempty"`
+	Annotations      []Annotation       `json:"annotations,omitempty"`
 }
 
 func (c *ChatCompletionsStreamResponseChoiceDelta) SetContentString(s string) {
@@ -84,6 +135,13 @@ func (c *ChatCompletionsStreamResponseChoiceDelta) SetReasoningContent(s string)
 	c.Reasoning = &s
 }
 
+func (c *ChatCompletionsStreamResponseChoiceDelta) GetRefusal() string {
+	if c.Refusal == nil {
+		return ""
+	}
+	return *c.Refusal
+}
+
 type ToolCallResponse struct {
 	// Index is not nil only in chat completion chunk object
 	Index    *int             `json:"index,omitempty"`
@@ -112,6 +170,7 @@ type ChatCompletionsStreamResponse struct {
 	SystemFingerprint *string                               `json:"system_fingerprint"`
 	Choices           []ChatCompletionsStreamResponseChoice `json:"choices"`
 	Usage             *Usage                                `json:"usage"`
+	Citations         []string                              `json:"citations,omitempty"`
 }
 
 func (c *ChatCompletionsStreamResponse) IsToolCall() bool {
@@ -139,6 +198,7 @@ func (c *ChatCompletionsStreamResponse) Copy() *ChatCompletionsStreamResponse {
 		SystemFingerprint: c.SystemFingerprint,
 		Choices:           choices,
 		Usage:             c.Usage,
+		Citations:         c.Citations,
 	}
 }
 
@@ -3,6 +3,7 @@ package relay
 import (
 	"ask_terminal/dto"
 	"context"
+	"time"
 )
 
 // AIAdapter defines the interface for AI service adapters
@@ -10,10 +11,19 @@ type AIAdapter interface {
 	ProcessQuery(query string) (string, error)
 }
 
+// ProviderCapabilities lists the optional features an adapter can use, so callers
+// can adjust or reject a request up front instead of getting a provider error
+type ProviderCapabilities struct {
+	JSONMode    bool // Honors response_format json_object
+	Tools       bool // Passes tool definitions and tool calls through
+	Vision      bool // Accepts images in user messages
+	StreamUsage bool // Reports token usage at the end of a stream
+}
+
 // Adapter defines the complete adapter interface for API interactions
 type Adapter interface {
-	// Initialize the adapter with configuration
-	Init(baseURL, apiKey string, proxyURL string) error
+	// Initialize the adapter with configuration; httpTimeout of 0 means no overall limit
+	Init(baseURL, apiKey string, proxyURL string, httpTimeout time.Duration) error
 
 	// Send a chat completion request
 	ChatCompletion(ctx context.Context, request *dto.GeneralOpenAIRequest) (*dto.OpenAITextResponse, error)
@@ -21,6 +31,15 @@ type Adapter interface {
 	// Send a streaming chat completion request
 	ChatCompletionStream(ctx context.Context, request *dto.GeneralOpenAIRequest) (chan *dto.ChatCompletionsStreamResponse, error)
 
+	// Embed each input text, returning one vector per input in order
+	Embeddings(ctx context.Context, input []string, model string) ([][]float32, error)
+
+	// Send a request body to the chat endpoint unchanged, returning the status and raw response body
+	RawRequest(ctx context.Context, body []byte) (int, []byte, error)
+
+	// Report which optional features this provider supports
+	Capabilities() ProviderCapabilities
+
 	// Process a simple query (for AIAdapter compatibility)
 	ProcessQuery(query string) (string, error)
 }
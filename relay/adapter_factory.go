@@ -2,21 +2,73 @@ package relay
 
 import (
 	"fmt"
+	"time"
 
 	"ask_terminal/config"
 )
 
 // NewAdapter returns the appropriate adapter based on the provider configuration
 func NewAdapter(conf *config.Config) (Adapter, error) { // Use the Adapter type from adapter.go
-	// For now, we only support OpenAI-compatible adapter
-	if conf.Provider == "openai-compatible" || conf.Provider == "" {
-		adapter := NewOpenAIAdapter()
-		err := adapter.Init(conf.BaseURL, conf.APIKey, conf.Proxy)
-		if err != nil {
-			return nil, fmt.Errorf("failed to initialize adapter: %w", err) // Wrap error for context
+	if conf.Debug {
+		EnableDebugLogging()
+	}
+	failures, window, cooldown := conf.BreakerSettings()
+	ConfigureBreaker(BreakerSettings{Failures: failures, Window: window, Cooldown: cooldown})
+
+	var adapter Adapter
+	switch conf.Provider {
+	case "openai-compatible", "":
+		adapter = NewOpenAIAdapter()
+	case "anthropic":
+		adapter = NewAnthropicAdapter()
+	case "ollama":
+		adapter = NewOllamaAdapter()
+	default:
+		return nil, fmt.Errorf("unsupported provider: %s (available: openai-compatible, anthropic, ollama)", conf.Provider)
+	}
+
+	err := adapter.Init(conf.BaseURL, conf.APIKey, conf.Proxy, time.Duration(conf.HTTPTimeout)*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize adapter: %w", err) // Wrap error for context
+	}
+
+	if err := applyCapabilities(conf, adapter.Capabilities()); err != nil {
+		return nil, err
+	}
+
+	// Adapters for gateways with other authentication schemes get the configured one
+	if authorizer, ok := adapter.(interface {
+		SetAuthStyle(style, headerName, queryParam string) error
+	}); ok {
+		if err := authorizer.SetAuthStyle(conf.AuthStyle, conf.AuthHeaderName, conf.AuthQueryParam); err != nil {
+			return nil, err
 		}
-		return adapter, nil // Return nil error on success
 	}
 
-	return nil, fmt.Errorf("unsupported provider: %s", conf.Provider)
+	// Adapters whose provider caches prompts by explicit breakpoints get the setting
+	if cacher, ok := adapter.(interface{ SetPromptCache(bool) }); ok {
+		cacher.SetPromptCache(conf.PromptCache)
+	}
+
+	// Adapters that can retry transient failures get the configured policy
+	if retrier, ok := adapter.(interface{ SetMaxRetries(int) }); ok {
+		retrier.SetMaxRetries(conf.RetryCount())
+	}
+	return adapter, nil // Return nil error on success
+}
+
+// applyCapabilities turns off optional features the provider lacks, and rejects
+// explicitly requested ones, before any request is built
+func applyCapabilities(conf *config.Config, caps ProviderCapabilities) error {
+	if len(conf.Images) > 0 && !caps.Vision {
+		return fmt.Errorf("provider %s does not accept images (--image)", conf.Provider)
+	}
+	if !caps.JSONMode {
+		if conf.ResponseFormat == "json_object" {
+			return fmt.Errorf("provider %s does not support response_format json_object", conf.Provider)
+		}
+		// Command suggestions fall back to the plain-text format
+		conf.NoJSON = true
+	}
+	return nil
 }
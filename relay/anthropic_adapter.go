@@ -0,0 +1,427 @@
+package relay
+
+import (
+	"ask_terminal/common"
+	"ask_terminal/dto"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// anthropicVersion is the API version sent in the anthropic-version header
+	anthropicVersion = "2023-06-01"
+	// defaultAnthropicMaxTokens is used when max_tokens is 0, Anthropic requires a limit
+	defaultAnthropicMaxTokens = 4096
+	// defaultAnthropicModel is used by ProcessQuery
+	defaultAnthropicModel = "claude-3-5-haiku-latest"
+)
+
+// AnthropicAdapter talks to Anthropic's native messages API
+type AnthropicAdapter struct {
+	baseURL     string
+	apiKey      string
+	proxyURL    string
+	httpTimeout time.Duration
+	maxRetries  int
+	promptCache bool
+	client      *http.Client
+}
+
+func NewAnthropicAdapter() *AnthropicAdapter {
+	return &AnthropicAdapter{
+		client: &http.Client{},
+	}
+}
+
+func (a *AnthropicAdapter) Init(baseURL, apiKey string, proxyURL string, httpTimeout time.Duration) error {
+	if apiKey == "" {
+		return fmt.Errorf("apiKey cannot be empty")
+	}
+
+	// The OpenAI default means base_url wasn't set for Anthropic
+	if baseURL == "" || baseURL == common.DefaultBaseURL {
+		baseURL = common.DefaultAnthropicBaseURL
+	}
+
+	// Ensure URL ends with a "/" for proper endpoint joining
+	a.baseURL = strings.TrimRight(baseURL, "/") + "/"
+	a.apiKey = apiKey
+	a.proxyURL = proxyURL
+	a.httpTimeout = httpTimeout
+
+	client, err := newHTTPClient(proxyURL, httpTimeout)
+	if err != nil {
+		return err
+	}
+	a.client = client
+
+	return nil
+}
+
+// SetMaxRetries sets how often rate-limited, failed or timed out requests are retried
+func (a *AnthropicAdapter) SetMaxRetries(maxRetries int) {
+	a.maxRetries = maxRetries
+}
+
+// SetPromptCache makes the system prompt a prompt caching breakpoint
+func (a *AnthropicAdapter) SetPromptCache(enabled bool) {
+	a.promptCache = enabled
+}
+
+// Capabilities reports what the messages translation supports; there is no JSON mode
+func (a *AnthropicAdapter) Capabilities() ProviderCapabilities {
+	return ProviderCapabilities{Vision: true, StreamUsage: true}
+}
+
+func (a *AnthropicAdapter) ChatCompletion(ctx context.Context, request *dto.GeneralOpenAIRequest) (*dto.OpenAITextResponse, error) {
+	resp, err := a.send(ctx, toAnthropicRequest(request, false, a.promptCache))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp.StatusCode, body)
+	}
+
+	var result dto.AnthropicResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	// Join the text blocks into one OpenAI style message
+	var text, thinking strings.Builder
+	for _, block := range result.Content {
+		switch block.Type {
+		case "text":
+			text.WriteString(block.Text)
+		case "thinking":
+			thinking.WriteString(block.Thinking)
+		}
+	}
+	message := dto.Message{Role: "assistant", ReasoningContent: thinking.String()}
+	message.SetStringContent(text.String())
+
+	return &dto.OpenAITextResponse{
+		Id:     result.Id,
+		Model:  result.Model,
+		Object: "chat.completion",
+		Choices: []dto.OpenAITextResponseChoice{{
+			Message:      message,
+			FinishReason: anthropicFinishReason(result.StopReason),
+		}},
+		Usage: anthropicUsage(result.Usage),
+	}, nil
+}
+
+func (a *AnthropicAdapter) ChatCompletionStream(ctx context.Context, request *dto.GeneralOpenAIRequest) (chan *dto.ChatCompletionsStreamResponse, error) {
+	resp, err := a.send(ctx, toAnthropicRequest(request, true, a.promptCache))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return nil, newAPIError(resp.StatusCode, body)
+	}
+
+	responseChannel := make(chan *dto.ChatCompletionsStreamResponse)
+
+	go func() {
+		defer resp.Body.Close()
+		defer close(responseChannel)
+
+		reader := bufio.NewReader(resp.Body)
+		var id, model string
+		var usage dto.AnthropicUsage
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+
+			line, err := reader.ReadBytes('\n')
+			if err != nil {
+				if err != io.EOF {
+					debugf("Error reading stream: %v", err)
+				}
+				return
+			}
+
+			// The event type is repeated in the data, so "event:" lines are skipped
+			line = bytes.TrimSpace(line)
+			if !bytes.HasPrefix(line, []byte("data:")) {
+				continue
+			}
+			data := bytes.TrimSpace(bytes.TrimPrefix(line, []byte("data:")))
+
+			var event dto.AnthropicStreamEvent
+			if err := json.Unmarshal(data, &event); err != nil {
+				debugf("Error parsing stream response: %v", err)
+				continue
+			}
+
+			chunk := &dto.ChatCompletionsStreamResponse{Id: id, Object: "chat.completion.chunk", Model: model}
+			switch event.Type {
+			case "message_start":
+				if event.Message != nil {
+					id, model = event.Message.Id, event.Message.Model
+					usage = event.Message.Usage
+				}
+				continue
+
+			case "content_block_delta":
+				if event.Delta == nil {
+					continue
+				}
+				var delta dto.ChatCompletionsStreamResponseChoiceDelta
+				switch event.Delta.Type {
+				case "text_delta":
+					delta.SetContentString(event.Delta.Text)
+				case "thinking_delta":
+					delta.SetReasoningContent(event.Delta.Thinking)
+				default:
+					continue
+				}
+				chunk.Choices = []dto.ChatCompletionsStreamResponseChoice{{Delta: delta}}
+
+			case "message_delta":
+				if event.Usage != nil {
+					usage.OutputTokens = event.Usage.OutputTokens
+				}
+				if event.Delta == nil || event.Delta.StopReason == "" {
+					continue
+				}
+				finishReason := anthropicFinishReason(event.Delta.StopReason)
+				total := anthropicUsage(usage)
+				chunk.Choices = []dto.ChatCompletionsStreamResponseChoice{{FinishReason: &finishReason}}
+				chunk.Usage = &total
+
+			case "message_stop":
+				return
+
+			case "error":
+				if event.Error != nil {
+					debugf("Anthropic stream error: %s", event.Error.Message)
+				}
+				return
+
+			default:
+				// ping, content_block_start and content_block_stop carry nothing to pass on
+				continue
+			}
+
+			responseChannel <- chunk
+		}
+	}()
+
+	return responseChannel, nil
+}
+
+// Embeddings isn't offered by Anthropic's API
+func (a *AnthropicAdapter) Embeddings(ctx context.Context, input []string, model string) ([][]float32, error) {
+	return nil, fmt.Errorf("the anthropic provider doesn't support embeddings, use an openai-compatible provider")
+}
+
+// RawRequest posts body to the messages endpoint as is, for debugging.
+// It is never retried, so the answer shown is the one the provider gave first.
+func (a *AnthropicAdapter) RawRequest(ctx context.Context, body []byte) (int, []byte, error) {
+	newRequest := func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", a.baseURL+"messages", bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		a.setHeaders(req)
+		return req, nil
+	}
+
+	resp, err := doWithRetry(ctx, a.client, 0, newRequest)
+	if err != nil {
+		return 0, nil, wrapRequestError(err, a.baseURL, a.httpTimeout)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	return resp.StatusCode, respBody, nil
+}
+
+// ProcessQuery implements the AIAdapter interface for simple query processing
+func (a *AnthropicAdapter) ProcessQuery(query string) (string, error) {
+	request := &dto.GeneralOpenAIRequest{
+		Model:    defaultAnthropicModel,
+		Messages: []dto.Message{{Role: "user"}},
+	}
+	request.Messages[0].SetStringContent(query)
+
+	response, err := a.ChatCompletion(context.Background(), request)
+	if err != nil {
+		return "", err
+	}
+	if len(response.Choices) > 0 {
+		return response.Choices[0].Message.StringContent(), nil
+	}
+	return "", nil
+}
+
+// send posts a messages request
+func (a *AnthropicAdapter) send(ctx context.Context, request *dto.AnthropicRequest) (*http.Response, error) {
+	jsonData, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	newRequest := func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", a.baseURL+"messages", bytes.NewReader(jsonData))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		a.setHeaders(req)
+		if request.Stream {
+			req.Header.Set("Accept", "text/event-stream")
+		}
+		return req, nil
+	}
+
+	// Back off proactively when the provider said the limit is nearly exhausted
+	if err := waitForRateLimit(ctx); err != nil {
+		return nil, err
+	}
+
+	// Only the initial request is retried, a stream that breaks midway is not
+	resp, err := doWithRetry(ctx, a.client, a.maxRetries, newRequest)
+	if err != nil {
+		return nil, wrapRequestError(err, a.baseURL, a.httpTimeout)
+	}
+	return resp, nil
+}
+
+// setHeaders adds the authentication and version headers Anthropic expects
+func (a *AnthropicAdapter) setHeaders(req *http.Request) {
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", a.apiKey)
+	req.Header.Set("anthropic-version", anthropicVersion)
+}
+
+// toAnthropicRequest translates an OpenAI style request into a messages request,
+// moving system messages into the system field. With cache the end of the system
+// prompt is marked as a caching breakpoint.
+func toAnthropicRequest(request *dto.GeneralOpenAIRequest, stream bool, cache bool) *dto.AnthropicRequest {
+	result := &dto.AnthropicRequest{
+		Model:         request.Model,
+		MaxTokens:     request.MaxTokens,
+		Stream:        stream,
+		Temperature:   request.Temperature,
+		TopP:          request.TopP,
+		StopSequences: request.Stop,
+	}
+	if result.MaxTokens == 0 {
+		result.MaxTokens = defaultAnthropicMaxTokens
+	}
+
+	var system []dto.AnthropicContent
+	for i := range request.Messages {
+		message := &request.Messages[i]
+		blocks := toAnthropicContent(message)
+		if message.Role == "system" {
+			system = append(system, blocks...)
+			continue
+		}
+
+		// Tool results and other roles are sent as user turns
+		role := message.Role
+		if role != "assistant" {
+			role = "user"
+		}
+		result.Messages = append(result.Messages, dto.AnthropicMessage{Role: role, Content: blocks})
+	}
+
+	// A plain string is enough unless the system prompt is a caching breakpoint
+	if cache && len(system) > 0 {
+		system[len(system)-1].CacheControl = &dto.CacheControl{Type: dto.CacheControlEphemeral}
+		result.System = system
+	} else if len(system) > 0 {
+		texts := make([]string, 0, len(system))
+		for _, block := range system {
+			texts = append(texts, block.Text)
+		}
+		result.System = strings.Join(texts, "\n\n")
+	}
+
+	return result
+}
+
+// toAnthropicContent converts a message's content into Anthropic content blocks
+func toAnthropicContent(message *dto.Message) []dto.AnthropicContent {
+	if message.IsStringContent() {
+		return []dto.AnthropicContent{{Type: "text", Text: message.StringContent()}}
+	}
+
+	var blocks []dto.AnthropicContent
+	for _, content := range message.ParseContent() {
+		switch content.Type {
+		case dto.ContentTypeText:
+			blocks = append(blocks, dto.AnthropicContent{Type: "text", Text: content.Text})
+		case dto.ContentTypeImageURL:
+			if content.ImageUrl != nil {
+				blocks = append(blocks, dto.AnthropicContent{Type: "image", Source: anthropicImageSource(content.ImageUrl.Url)})
+			}
+		}
+	}
+	return blocks
+}
+
+// anthropicImageSource turns an image URL, possibly a base64 data URL, into an image source
+func anthropicImageSource(url string) *dto.AnthropicImageSource {
+	if strings.HasPrefix(url, "data:") {
+		if header, data, ok := strings.Cut(strings.TrimPrefix(url, "data:"), ","); ok {
+			return &dto.AnthropicImageSource{
+				Type:      "base64",
+				MediaType: strings.TrimSuffix(header, ";base64"),
+				Data:      data,
+			}
+		}
+	}
+	return &dto.AnthropicImageSource{Type: "url", URL: url}
+}
+
+// anthropicFinishReason maps a stop_reason to the OpenAI finish_reason
+func anthropicFinishReason(stopReason string) string {
+	switch stopReason {
+	case "max_tokens":
+		return "length"
+	case "tool_use":
+		return "tool_calls"
+	case "refusal":
+		return dto.FinishReasonContentFilter
+	default:
+		return "stop"
+	}
+}
+
+// anthropicUsage maps Anthropic token counts to OpenAI usage
+func anthropicUsage(usage dto.AnthropicUsage) dto.Usage {
+	prompt := usage.InputTokens + usage.CacheReadInputTokens + usage.CacheCreationInputTokens
+	return dto.Usage{
+		PromptTokens:         prompt,
+		CompletionTokens:     usage.OutputTokens,
+		TotalTokens:          prompt + usage.OutputTokens,
+		PromptCacheHitTokens: usage.CacheReadInputTokens,
+	}
+}
@@ -0,0 +1,185 @@
+package relay
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"ask_terminal/utils"
+)
+
+// BreakerSettings configures the circuit breaker shared by every request to a host
+type BreakerSettings struct {
+	Failures int           // Consecutive failures that open the breaker (0 disables it)
+	Window   time.Duration // Failures further apart than this start a new count
+	Cooldown time.Duration // How long requests are refused once the breaker is open
+}
+
+// breakerSettings is set from the config by NewAdapter
+var breakerSettings = BreakerSettings{Failures: 5, Window: time.Minute, Cooldown: 30 * time.Second}
+
+// breakerMu serializes reading and writing the state file within a process
+var breakerMu sync.Mutex
+
+// BreakerState is the failure record of one host, kept across runs
+type BreakerState struct {
+	Failures     int       `json:"failures"`
+	FirstFailure time.Time `json:"first_failure"`
+	LastError    string    `json:"last_error,omitempty"`
+	OpenUntil    time.Time `json:"open_until"`
+}
+
+// Open reports whether requests to the host are currently refused
+func (s *BreakerState) Open(now time.Time) bool {
+	return s != nil && s.OpenUntil.After(now)
+}
+
+// CircuitOpenError is returned instead of sending a request while the breaker is open
+type CircuitOpenError struct {
+	Host     string
+	Failures int
+	Until    time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("%s failed %d times in a row, not sending requests for another %s (breaker_cooldown)",
+		e.Host, e.Failures, time.Until(e.Until).Round(time.Second))
+}
+
+// breakerStatePath returns the file used to share breaker state between runs
+func breakerStatePath() string {
+	return filepath.Join(utils.DataDir(), "askta_breaker.json")
+}
+
+// LoadBreakerStates reads the saved breaker state of every host
+func LoadBreakerStates() (map[string]*BreakerState, error) {
+	data, err := os.ReadFile(breakerStatePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]*BreakerState{}, nil
+		}
+		return nil, fmt.Errorf("failed to read breaker state: %w", err)
+	}
+
+	states := map[string]*BreakerState{}
+	if err := json.Unmarshal(data, &states); err != nil {
+		return nil, fmt.Errorf("failed to parse breaker state: %w", err)
+	}
+	return states, nil
+}
+
+// saveBreakerStates stores the breaker state for the next request or run
+func saveBreakerStates(states map[string]*BreakerState) error {
+	data, err := json.Marshal(states)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(breakerStatePath(), data, 0600)
+}
+
+// checkBreaker returns a CircuitOpenError while requests to host are refused. Once
+// the cooldown ends one request goes through; another failure reopens the breaker.
+func checkBreaker(host string) error {
+	if breakerSettings.Failures <= 0 {
+		return nil
+	}
+
+	breakerMu.Lock()
+	defer breakerMu.Unlock()
+	states, err := LoadBreakerStates()
+	if err != nil {
+		utils.LogError("Failed to load breaker state", err)
+		return nil
+	}
+	if state := states[host]; state.Open(time.Now()) {
+		return &CircuitOpenError{Host: host, Failures: state.Failures, Until: state.OpenUntil}
+	}
+	return nil
+}
+
+// recordBreakerResult counts a failed attempt against host, opening the breaker at the
+// threshold, or clears the count after a success
+func recordBreakerResult(host string, failure error) {
+	if breakerSettings.Failures <= 0 {
+		return
+	}
+
+	breakerMu.Lock()
+	defer breakerMu.Unlock()
+	states, err := LoadBreakerStates()
+	if err != nil {
+		states = map[string]*BreakerState{}
+	}
+
+	if failure == nil {
+		if _, ok := states[host]; !ok {
+			return
+		}
+		delete(states, host)
+	} else {
+		now := time.Now()
+		state := states[host]
+		// A failure after the cooldown reopens the breaker, otherwise old failures expire
+		halfOpen := state != nil && state.Failures >= breakerSettings.Failures
+		if state == nil || !halfOpen && now.Sub(state.FirstFailure) > breakerSettings.Window {
+			state = &BreakerState{FirstFailure: now}
+			states[host] = state
+		}
+		state.Failures++
+		state.LastError = failure.Error()
+		if state.Failures >= breakerSettings.Failures {
+			state.OpenUntil = now.Add(breakerSettings.Cooldown)
+			utils.LogInfo(fmt.Sprintf("Circuit breaker opened for %s after %d failures, cooling down for %s",
+				host, state.Failures, breakerSettings.Cooldown))
+		}
+	}
+
+	if err := saveBreakerStates(states); err != nil {
+		utils.LogError("Failed to save breaker state", err)
+	}
+}
+
+// ConfigureBreaker sets the breaker thresholds used by later requests
+func ConfigureBreaker(settings BreakerSettings) {
+	breakerSettings = settings
+}
+
+// FormatBreakerStates renders the breaker state of every host for --doctor
+func FormatBreakerStates(states map[string]*BreakerState) string {
+	if breakerSettings.Failures <= 0 {
+		return "Circuit breaker: disabled (breaker_failures: 0)\n"
+	}
+
+	now := time.Now()
+	var s strings.Builder
+	s.WriteString(fmt.Sprintf("Circuit breaker: opens after %d failures within %s, for %s\n",
+		breakerSettings.Failures, breakerSettings.Window, breakerSettings.Cooldown))
+	if len(states) == 0 {
+		s.WriteString("  No recent failures.\n")
+		return s.String()
+	}
+
+	hosts := make([]string, 0, len(states))
+	for host := range states {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		state := states[host]
+		status := "closed"
+		if state.Open(now) {
+			status = fmt.Sprintf("OPEN, retrying in %s", state.OpenUntil.Sub(now).Round(time.Second))
+		}
+		s.WriteString(fmt.Sprintf("  %s: %s, %d consecutive failures", host, status, state.Failures))
+		if state.LastError != "" {
+			s.WriteString(" (last: " + state.LastError + ")")
+		}
+		s.WriteString("\n")
+	}
+	return s.String()
+}
@@ -0,0 +1,80 @@
+package relay
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// debugLogging enables request and response dumps on stderr. It is turned on by
+// the debug config setting or by setting ASKTA_DEBUG in the environment.
+var debugLogging = os.Getenv("ASKTA_DEBUG") != ""
+
+// EnableDebugLogging turns on request and response dumps
+func EnableDebugLogging() {
+	debugLogging = true
+}
+
+// debugf logs to stderr only when debug logging is on
+func debugf(format string, args ...any) {
+	if debugLogging {
+		log.Printf(format, args...)
+	}
+}
+
+// secretHeaders and secretQueryParams name the places credentials are sent in; the
+// auth_style settings add the configured ones
+var (
+	secretHeaders     = []string{"Authorization", "X-Api-Key", "Api-Key"}
+	secretQueryParams []string
+)
+
+// redactHeaderName masks the header in debug output
+func redactHeaderName(name string) {
+	secretHeaders = append(secretHeaders, name)
+}
+
+// redactQueryParam masks the query parameter in debug output and request errors
+func redactQueryParam(name string) {
+	secretQueryParams = append(secretQueryParams, name)
+}
+
+// redactURL returns the URL with credential query parameters masked
+func redactURL(u *url.URL) string {
+	if len(secretQueryParams) == 0 || u.RawQuery == "" {
+		return u.String()
+	}
+	redacted := *u
+	query := redacted.Query()
+	for _, name := range secretQueryParams {
+		if query.Has(name) {
+			query.Set(name, "REDACTED")
+		}
+	}
+	redacted.RawQuery = query.Encode()
+	return redacted.String()
+}
+
+// redactURLError masks credentials in the URL quoted by an HTTP client error
+func redactURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		if u, parseErr := url.Parse(urlErr.URL); parseErr == nil {
+			urlErr.URL = redactURL(u)
+		}
+	}
+	return err
+}
+
+// redactHeaders returns a copy of the headers with credentials masked
+func redactHeaders(header http.Header) http.Header {
+	redacted := header.Clone()
+	for _, name := range secretHeaders {
+		if redacted.Get(name) != "" {
+			redacted.Set(name, "[REDACTED]")
+		}
+	}
+	return redacted
+}
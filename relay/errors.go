@@ -0,0 +1,55 @@
+package relay
+
+import (
+	"ask_terminal/dto"
+	"encoding/json"
+	"fmt"
+)
+
+// APIError is a non-200 answer from the provider
+type APIError struct {
+	StatusCode int
+	Message    string // Provider error message, empty when the body couldn't be parsed
+	Body       string // Raw response body
+	details    *dto.GeneralErrorResponse
+}
+
+func (e *APIError) Error() string {
+	// Say what to fix instead of dumping the provider's error JSON
+	if e.IsAuth() {
+		message := e.authHint()
+		if e.Message != "" {
+			message += " (provider said: " + e.Message + ")"
+		}
+		return message
+	}
+	if e.details == nil {
+		return fmt.Sprintf("unexpected status code: %d, body: %s", e.StatusCode, e.Body)
+	}
+	return fmt.Sprintf("API error: %s (Status code: %d) - Error: %+v", e.Message, e.StatusCode, *e.details)
+}
+
+// IsAuth reports whether the provider rejected the credentials
+func (e *APIError) IsAuth() bool {
+	return e.StatusCode == 401 || e.StatusCode == 403
+}
+
+// authHint explains a 401 or 403 answer
+func (e *APIError) authHint() string {
+	if e.StatusCode == 403 {
+		return "access denied (403): the API key lacks permission for this model or endpoint"
+	}
+	return "authentication failed (401): check your API key (api_key in the config, or --key)"
+}
+
+// newAPIError builds an APIError from a status code and response body
+func newAPIError(statusCode int, body []byte) *APIError {
+	apiErr := &APIError{StatusCode: statusCode, Body: string(body)}
+	var errResp dto.GeneralErrorResponse
+	if err := json.Unmarshal(body, &errResp); err == nil {
+		apiErr.details = &errResp
+		apiErr.Message = errResp.ToMessage()
+	}
+	debugf("API error response (status %d): %s", statusCode, string(body))
+	return apiErr
+}
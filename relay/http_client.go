@@ -0,0 +1,85 @@
+package relay
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// defaultConnectTimeout bounds dialing and the TLS handshake when no http_timeout is set
+const defaultConnectTimeout = 10 * time.Second
+
+// newHTTPClient builds an HTTP client with optional proxy and timeouts.
+// httpTimeout limits the whole request including the response body; when it is
+// zero only the connect phase is bounded so long streaming answers still work.
+func newHTTPClient(proxyURL string, httpTimeout time.Duration) (*http.Client, error) {
+	connectTimeout := defaultConnectTimeout
+	if httpTimeout > 0 && httpTimeout < connectTimeout {
+		connectTimeout = httpTimeout
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   connectTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.TLSHandshakeTimeout = connectTimeout
+
+	// Route requests through the proxy if specified
+	if proxyURL != "" {
+		proxyURLParsed, err := parseProxyURL(proxyURL)
+		if err != nil {
+			return nil, err
+		}
+
+		switch proxyURLParsed.Scheme {
+		case "socks5", "socks5h":
+			// SOCKS proxies tunnel the connection itself rather than the HTTP request
+			socksDialer, err := proxy.FromURL(proxyURLParsed, dialer)
+			if err != nil {
+				return nil, fmt.Errorf("invalid proxy URL %q: %w", proxyURL, err)
+			}
+			contextDialer, ok := socksDialer.(proxy.ContextDialer)
+			if !ok {
+				return nil, fmt.Errorf("invalid proxy URL %q: SOCKS dialer does not support contexts", proxyURL)
+			}
+			transport.Proxy = nil
+			transport.DialContext = contextDialer.DialContext
+		default:
+			transport.Proxy = http.ProxyURL(proxyURLParsed)
+		}
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   httpTimeout,
+	}, nil
+}
+
+// parseProxyURL checks that a proxy URL has a supported scheme and a host, since
+// url.Parse accepts almost anything and a typo would otherwise bypass the proxy
+func parseProxyURL(proxyURL string) (*url.URL, error) {
+	parsed, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL %q: %w", proxyURL, err)
+	}
+
+	switch parsed.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		if !strings.Contains(proxyURL, "://") {
+			return nil, fmt.Errorf("invalid proxy URL %q: missing scheme (e.g. http://host:port or socks5://host:port)", proxyURL)
+		}
+		return nil, fmt.Errorf("invalid proxy URL %q: unsupported scheme %q (use http, https, socks5 or socks5h)", proxyURL, parsed.Scheme)
+	}
+	if parsed.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q: missing host", proxyURL)
+	}
+	return parsed, nil
+}
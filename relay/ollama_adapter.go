@@ -0,0 +1,348 @@
+package relay
+
+import (
+	"ask_terminal/common"
+	"ask_terminal/dto"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// defaultOllamaModel is used by ProcessQuery
+const defaultOllamaModel = "llama3.2"
+
+// OllamaAdapter talks to a local Ollama server's native chat API
+type OllamaAdapter struct {
+	baseURL     string
+	apiKey      string
+	proxyURL    string
+	httpTimeout time.Duration
+	maxRetries  int
+	client      *http.Client
+}
+
+func NewOllamaAdapter() *OllamaAdapter {
+	return &OllamaAdapter{
+		client: &http.Client{},
+	}
+}
+
+// Init configures the adapter; the API key is optional since local servers don't check one
+func (a *OllamaAdapter) Init(baseURL, apiKey string, proxyURL string, httpTimeout time.Duration) error {
+	// The OpenAI default means base_url wasn't set for Ollama
+	if baseURL == "" || baseURL == common.DefaultBaseURL {
+		baseURL = common.DefaultOllamaBaseURL
+	}
+
+	// Ensure URL ends with a "/" for proper endpoint joining
+	a.baseURL = strings.TrimRight(baseURL, "/") + "/"
+	a.apiKey = apiKey
+	a.proxyURL = proxyURL
+	a.httpTimeout = httpTimeout
+
+	client, err := newHTTPClient(proxyURL, httpTimeout)
+	if err != nil {
+		return err
+	}
+	a.client = client
+
+	return nil
+}
+
+// SetMaxRetries sets how often rate-limited, failed or timed out requests are retried
+func (a *OllamaAdapter) SetMaxRetries(maxRetries int) {
+	a.maxRetries = maxRetries
+}
+
+// Capabilities reports what the chat translation supports; tools aren't mapped
+func (a *OllamaAdapter) Capabilities() ProviderCapabilities {
+	return ProviderCapabilities{JSONMode: true, Vision: true, StreamUsage: true}
+}
+
+func (a *OllamaAdapter) ChatCompletion(ctx context.Context, request *dto.GeneralOpenAIRequest) (*dto.OpenAITextResponse, error) {
+	resp, err := a.post(ctx, "api/chat", toOllamaRequest(request, false))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp.StatusCode, body)
+	}
+
+	var result dto.OllamaResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	message := dto.Message{Role: "assistant", ReasoningContent: result.Message.Thinking}
+	message.SetStringContent(result.Message.Content)
+
+	return &dto.OpenAITextResponse{
+		Model:  result.Model,
+		Object: "chat.completion",
+		Choices: []dto.OpenAITextResponseChoice{{
+			Message:      message,
+			FinishReason: ollamaFinishReason(result.DoneReason),
+		}},
+		Usage: ollamaUsage(result),
+	}, nil
+}
+
+// ChatCompletionStream reads Ollama's newline-delimited JSON stream, where every
+// line is a complete object and the last one has done set
+func (a *OllamaAdapter) ChatCompletionStream(ctx context.Context, request *dto.GeneralOpenAIRequest) (chan *dto.ChatCompletionsStreamResponse, error) {
+	resp, err := a.post(ctx, "api/chat", toOllamaRequest(request, true))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return nil, newAPIError(resp.StatusCode, body)
+	}
+
+	responseChannel := make(chan *dto.ChatCompletionsStreamResponse)
+
+	go func() {
+		defer resp.Body.Close()
+		defer close(responseChannel)
+
+		reader := bufio.NewReader(resp.Body)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+
+			line, err := reader.ReadBytes('\n')
+			line = bytes.TrimSpace(line)
+			if len(line) > 0 {
+				var chunk dto.OllamaResponse
+				if jsonErr := json.Unmarshal(line, &chunk); jsonErr != nil {
+					debugf("Error parsing stream response: %v", jsonErr)
+				} else if chunk.Error != "" {
+					debugf("Ollama stream error: %s", chunk.Error)
+					return
+				} else {
+					responseChannel <- ollamaStreamChunk(chunk)
+					if chunk.Done {
+						return
+					}
+				}
+			}
+
+			if err != nil {
+				if err != io.EOF {
+					debugf("Error reading stream: %v", err)
+				}
+				return
+			}
+		}
+	}()
+
+	return responseChannel, nil
+}
+
+// Embeddings calls the embed endpoint and returns the vectors in input order
+func (a *OllamaAdapter) Embeddings(ctx context.Context, input []string, model string) ([][]float32, error) {
+	resp, err := a.post(ctx, "api/embed", dto.OllamaEmbedRequest{Model: model, Input: input})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp.StatusCode, body)
+	}
+
+	var result dto.OllamaEmbedResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if len(result.Embeddings) != len(input) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(input), len(result.Embeddings))
+	}
+	return result.Embeddings, nil
+}
+
+// RawRequest posts body to the chat endpoint as is, for debugging.
+// It is never retried, so the answer shown is the one the provider gave first.
+func (a *OllamaAdapter) RawRequest(ctx context.Context, body []byte) (int, []byte, error) {
+	newRequest := func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", a.baseURL+"api/chat", bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		a.setHeaders(req)
+		return req, nil
+	}
+
+	resp, err := doWithRetry(ctx, a.client, 0, newRequest)
+	if err != nil {
+		return 0, nil, wrapRequestError(err, a.baseURL, a.httpTimeout)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	return resp.StatusCode, respBody, nil
+}
+
+// ProcessQuery implements the AIAdapter interface for simple query processing
+func (a *OllamaAdapter) ProcessQuery(query string) (string, error) {
+	request := &dto.GeneralOpenAIRequest{
+		Model:    defaultOllamaModel,
+		Messages: []dto.Message{{Role: "user"}},
+	}
+	request.Messages[0].SetStringContent(query)
+
+	response, err := a.ChatCompletion(context.Background(), request)
+	if err != nil {
+		return "", err
+	}
+	if len(response.Choices) > 0 {
+		return response.Choices[0].Message.StringContent(), nil
+	}
+	return "", nil
+}
+
+// post sends a JSON body to an Ollama endpoint
+func (a *OllamaAdapter) post(ctx context.Context, endpoint string, payload any) (*http.Response, error) {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	newRequest := func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", a.baseURL+endpoint, bytes.NewReader(jsonData))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		a.setHeaders(req)
+		return req, nil
+	}
+
+	if err := waitForRateLimit(ctx); err != nil {
+		return nil, err
+	}
+
+	// Only the initial request is retried, a stream that breaks midway is not
+	resp, err := doWithRetry(ctx, a.client, a.maxRetries, newRequest)
+	if err != nil {
+		return nil, wrapRequestError(err, a.baseURL, a.httpTimeout)
+	}
+	return resp, nil
+}
+
+// setHeaders adds the content type, and authorization only when a key is configured
+// (e.g. for an Ollama server behind an authenticating proxy)
+func (a *OllamaAdapter) setHeaders(req *http.Request) {
+	req.Header.Set("Content-Type", "application/json")
+	if a.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+a.apiKey)
+	}
+}
+
+// toOllamaRequest translates an OpenAI style request into an Ollama chat request
+func toOllamaRequest(request *dto.GeneralOpenAIRequest, stream bool) *dto.OllamaRequest {
+	result := &dto.OllamaRequest{
+		Model:  request.Model,
+		Stream: stream,
+	}
+	if request.ResponseFormat != nil && request.ResponseFormat.Type == "json_object" {
+		result.Format = "json"
+	}
+	if request.Temperature != nil || request.TopP != nil || request.MaxTokens != 0 || len(request.Stop) > 0 {
+		result.Options = &dto.OllamaOptions{
+			Temperature: request.Temperature,
+			TopP:        request.TopP,
+			NumPredict:  request.MaxTokens,
+			Stop:        request.Stop,
+		}
+	}
+
+	for i := range request.Messages {
+		message := &request.Messages[i]
+		converted := dto.OllamaMessage{Role: message.Role}
+		if message.IsStringContent() {
+			converted.Content = message.StringContent()
+		} else {
+			for _, content := range message.ParseContent() {
+				switch content.Type {
+				case dto.ContentTypeText:
+					converted.Content += content.Text
+				case dto.ContentTypeImageURL:
+					// Ollama only takes inline images, as base64 without the data URL header
+					if content.ImageUrl != nil && strings.HasPrefix(content.ImageUrl.Url, "data:") {
+						if _, data, ok := strings.Cut(content.ImageUrl.Url, ","); ok {
+							converted.Images = append(converted.Images, data)
+						}
+					}
+				}
+			}
+		}
+		result.Messages = append(result.Messages, converted)
+	}
+
+	return result
+}
+
+// ollamaStreamChunk maps one streamed line to an OpenAI style chunk
+func ollamaStreamChunk(chunk dto.OllamaResponse) *dto.ChatCompletionsStreamResponse {
+	var delta dto.ChatCompletionsStreamResponseChoiceDelta
+	delta.SetContentString(chunk.Message.Content)
+	if chunk.Message.Thinking != "" {
+		delta.SetReasoningContent(chunk.Message.Thinking)
+	}
+
+	choice := dto.ChatCompletionsStreamResponseChoice{Delta: delta}
+	response := &dto.ChatCompletionsStreamResponse{
+		Object:  "chat.completion.chunk",
+		Model:   chunk.Model,
+		Choices: []dto.ChatCompletionsStreamResponseChoice{choice},
+	}
+	if chunk.Done {
+		finishReason := ollamaFinishReason(chunk.DoneReason)
+		usage := ollamaUsage(chunk)
+		response.Choices[0].FinishReason = &finishReason
+		response.Usage = &usage
+	}
+	return response
+}
+
+// ollamaFinishReason maps a done_reason to the OpenAI finish_reason
+func ollamaFinishReason(doneReason string) string {
+	if doneReason == "length" {
+		return "length"
+	}
+	return "stop"
+}
+
+// ollamaUsage maps Ollama's evaluation counts to OpenAI usage
+func ollamaUsage(response dto.OllamaResponse) dto.Usage {
+	return dto.Usage{
+		PromptTokens:     response.PromptEvalCount,
+		CompletionTokens: response.EvalCount,
+		TotalTokens:      response.PromptEvalCount + response.EvalCount,
+	}
+}
@@ -7,28 +7,36 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"net"
 	"net/http"
-	"net/url"
 	"strings"
+	"time"
 )
 
 type OpenAIAdapter struct {
-	baseURL  string
-	apiKey   string
-	proxyURL string
-	client   *http.Client
+	baseURL     string
+	apiKey      string
+	proxyURL    string
+	httpTimeout time.Duration
+	maxRetries  int
+	client      *http.Client
+
+	authStyle  string // "bearer", "header" or "query"
+	authHeader string // header carrying the key with the header style
+	authParam  string // query parameter carrying the key with the query style
 }
 
 func NewOpenAIAdapter() *OpenAIAdapter {
 	return &OpenAIAdapter{
-		client: &http.Client{},
+		client:    &http.Client{},
+		authStyle: "bearer",
 	}
 }
 
-func (a *OpenAIAdapter) Init(baseURL, apiKey string, proxyURL string) error {
+func (a *OpenAIAdapter) Init(baseURL, apiKey string, proxyURL string, httpTimeout time.Duration) error {
 	if apiKey == "" {
 		return fmt.Errorf("apiKey cannot be empty")
 	}
@@ -42,24 +50,67 @@ func (a *OpenAIAdapter) Init(baseURL, apiKey string, proxyURL string) error {
 	a.baseURL = strings.TrimRight(baseURL, "/") + "/"
 	a.apiKey = apiKey
 	a.proxyURL = proxyURL
+	a.httpTimeout = httpTimeout
 
-	// Create HTTP client with proxy if specified
-	if proxyURL != "" {
-		proxyURLParsed, err := url.Parse(proxyURL)
-		if err != nil {
-			return fmt.Errorf("invalid proxy URL: %w", err)
+	client, err := newHTTPClient(proxyURL, httpTimeout)
+	if err != nil {
+		return err
+	}
+	a.client = client
+
+	return nil
+}
+
+// Capabilities reports the features of OpenAI-compatible APIs; requests are sent as is,
+// but usage isn't requested for streams
+func (a *OpenAIAdapter) Capabilities() ProviderCapabilities {
+	return ProviderCapabilities{JSONMode: true, Tools: true, Vision: true}
+}
+
+// SetAuthStyle selects how the API key is sent: as "Authorization: Bearer" (bearer,
+// the default), in a custom header (header) or as a query parameter (query), for
+// gateways that don't accept bearer tokens
+func (a *OpenAIAdapter) SetAuthStyle(style, headerName, queryParam string) error {
+	switch style {
+	case "", "bearer":
+		a.authStyle = "bearer"
+	case "header":
+		if headerName == "" {
+			headerName = "api-key"
 		}
-		transport := &http.Transport{
-			Proxy: http.ProxyURL(proxyURLParsed),
+		a.authStyle, a.authHeader = style, headerName
+		redactHeaderName(headerName)
+	case "query":
+		if queryParam == "" {
+			queryParam = "key"
 		}
-		a.client = &http.Client{Transport: transport}
-	} else {
-		a.client = &http.Client{}
+		a.authStyle, a.authParam = style, queryParam
+		redactQueryParam(queryParam)
+	default:
+		return fmt.Errorf("unsupported auth_style: %s (available: bearer, header, query)", style)
 	}
-
 	return nil
 }
 
+// authorize adds the API key to a request the way the gateway expects it
+func (a *OpenAIAdapter) authorize(req *http.Request) {
+	switch a.authStyle {
+	case "header":
+		req.Header.Set(a.authHeader, a.apiKey)
+	case "query":
+		query := req.URL.Query()
+		query.Set(a.authParam, a.apiKey)
+		req.URL.RawQuery = query.Encode()
+	default:
+		req.Header.Set("Authorization", "Bearer "+a.apiKey)
+	}
+}
+
+// SetMaxRetries sets how often rate-limited, failed or timed out requests are retried
+func (a *OpenAIAdapter) SetMaxRetries(maxRetries int) {
+	a.maxRetries = maxRetries
+}
+
 func (a *OpenAIAdapter) ChatCompletion(ctx context.Context, request *dto.GeneralOpenAIRequest) (*dto.OpenAITextResponse, error) {
 	endpoint := "chat/completions"
 	url := a.baseURL + endpoint
@@ -69,29 +120,24 @@ func (a *OpenAIAdapter) ChatCompletion(ctx context.Context, request *dto.General
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+	newRequest := func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonData))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		a.authorize(req)
+		return req, nil
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+a.apiKey)
-
-	resp, err := a.client.Do(req)
-	// Log the request and response for debugging
-	bodyPreview, _ := io.ReadAll(resp.Body)
-	resp.Body = io.NopCloser(bytes.NewBuffer(bodyPreview)) // Reassign body for further use
-
-	// Replace the logging line after the client.Do(req) call with:
-
-	// log.Printf("Request URL: %s", url)
-	// log.Printf("Request Headers: %+v", req.Header)
-	// log.Printf("Request Body: %s", string(jsonData)) // We already have the request body in jsonData
-	// log.Printf("Response Status: %d", resp.StatusCode)
-	// log.Printf("Response Body: %s", string(bodyPreview))
+	// Back off proactively when the provider said the limit is nearly exhausted
+	if err := waitForRateLimit(ctx); err != nil {
+		return nil, err
+	}
 
+	resp, err := doWithRetry(ctx, a.client, a.maxRetries, newRequest)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return nil, wrapRequestError(err, a.baseURL, a.httpTimeout)
 	}
 	defer resp.Body.Close()
 
@@ -99,18 +145,11 @@ func (a *OpenAIAdapter) ChatCompletion(ctx context.Context, request *dto.General
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
+	debugf("Response status: %d", resp.StatusCode)
+	debugf("Response body: %s", string(body))
 
 	if resp.StatusCode != http.StatusOK {
-		var errResp dto.GeneralErrorResponse
-		if err := json.Unmarshal(body, &errResp); err == nil {
-			// Print full error details
-			log.Printf("Full API error response: %s", string(body))
-			return nil, fmt.Errorf("API error: %s (Status code: %d) - Error: %+v",
-				errResp.ToMessage(),
-				resp.StatusCode,
-				errResp)
-		}
-		return nil, fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(body))
+		return nil, newAPIError(resp.StatusCode, body)
 	}
 
 	var result dto.OpenAITextResponse
@@ -133,32 +172,34 @@ func (a *OpenAIAdapter) ChatCompletionStream(ctx context.Context, request *dto.G
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+	newRequest := func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonData))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		a.authorize(req)
+		req.Header.Set("Accept", "text/event-stream")
+		return req, nil
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+a.apiKey)
-	req.Header.Set("Accept", "text/event-stream")
+	// Back off proactively when the provider said the limit is nearly exhausted
+	if err := waitForRateLimit(ctx); err != nil {
+		return nil, err
+	}
 
-	resp, err := a.client.Do(req)
+	// Only the initial request is retried, a stream that breaks midway is not
+	resp, err := doWithRetry(ctx, a.client, a.maxRetries, newRequest)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return nil, wrapRequestError(err, a.baseURL, a.httpTimeout)
 	}
 
 	if resp.StatusCode != http.StatusOK {
 		defer resp.Body.Close()
 		body, _ := io.ReadAll(resp.Body)
-		log.Printf("Full API error response (stream): %s", string(body))
-		var errResp dto.GeneralErrorResponse
-		if err := json.Unmarshal(body, &errResp); err == nil {
-			return nil, fmt.Errorf("API error: %s (Status code: %d) - Error: %+v",
-				errResp.ToMessage(),
-				resp.StatusCode,
-				errResp)
-		}
-		return nil, fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(body))
+		debugf("Response status: %d", resp.StatusCode)
+		debugf("Response body: %s", string(body))
+		return nil, newAPIError(resp.StatusCode, body)
 	}
 
 	responseChannel := make(chan *dto.ChatCompletionsStreamResponse)
@@ -177,7 +218,7 @@ func (a *OpenAIAdapter) ChatCompletionStream(ctx context.Context, request *dto.G
 				line, err := reader.ReadBytes('\n')
 				if err != nil {
 					if err != io.EOF {
-						log.Printf("Error reading stream: %v", err)
+						debugf("Error reading stream: %v", err)
 					}
 					return
 				}
@@ -197,7 +238,7 @@ func (a *OpenAIAdapter) ChatCompletionStream(ctx context.Context, request *dto.G
 
 					var streamResponse dto.ChatCompletionsStreamResponse
 					if err := json.Unmarshal(data, &streamResponse); err != nil {
-						log.Printf("Error parsing stream response: %v", err)
+						debugf("Error parsing stream response: %v", err)
 						continue
 					}
 
@@ -210,6 +251,92 @@ func (a *OpenAIAdapter) ChatCompletionStream(ctx context.Context, request *dto.G
 	return responseChannel, nil
 }
 
+// RawRequest posts body to the chat completions endpoint as is, for debugging.
+// It is never retried, so the answer shown is the one the provider gave first.
+func (a *OpenAIAdapter) RawRequest(ctx context.Context, body []byte) (int, []byte, error) {
+	newRequest := func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", a.baseURL+"chat/completions", bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		a.authorize(req)
+		return req, nil
+	}
+
+	resp, err := doWithRetry(ctx, a.client, 0, newRequest)
+	if err != nil {
+		return 0, nil, wrapRequestError(err, a.baseURL, a.httpTimeout)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	return resp.StatusCode, respBody, nil
+}
+
+// Embeddings calls the embeddings endpoint and returns the vectors in input order
+func (a *OpenAIAdapter) Embeddings(ctx context.Context, input []string, model string) ([][]float32, error) {
+	jsonData, err := json.Marshal(dto.EmbeddingRequest{Model: model, Input: input})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	newRequest := func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", a.baseURL+"embeddings", bytes.NewReader(jsonData))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		a.authorize(req)
+		return req, nil
+	}
+
+	if err := waitForRateLimit(ctx); err != nil {
+		return nil, err
+	}
+
+	resp, err := doWithRetry(ctx, a.client, a.maxRetries, newRequest)
+	if err != nil {
+		return nil, wrapRequestError(err, a.baseURL, a.httpTimeout)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp.StatusCode, body)
+	}
+
+	var result dto.OpenAIEmbeddingResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	vectors := make([][]float32, len(input))
+	for _, item := range result.Data {
+		if item.Index < 0 || item.Index >= len(vectors) {
+			return nil, fmt.Errorf("embedding index %d out of range", item.Index)
+		}
+		vector := make([]float32, len(item.Embedding))
+		for i, v := range item.Embedding {
+			vector[i] = float32(v)
+		}
+		vectors[item.Index] = vector
+	}
+	for i, vector := range vectors {
+		if vector == nil {
+			return nil, fmt.Errorf("no embedding returned for input %d", i)
+		}
+	}
+
+	return vectors, nil
+}
+
 // ProcessQuery implements the AIAdapter interface for simple query processing
 func (a *OpenAIAdapter) ProcessQuery(query string) (string, error) {
 	ctx := context.Background()
@@ -236,3 +363,16 @@ func (a *OpenAIAdapter) ProcessQuery(query string) (string, error) {
 
 	return "", nil
 }
+
+// wrapRequestError turns transport errors into readable messages, calling out timeouts
+func wrapRequestError(err error, baseURL string, httpTimeout time.Duration) error {
+	err = redactURLError(err)
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		if httpTimeout > 0 {
+			return fmt.Errorf("request to %s timed out (http_timeout: %s): %w", baseURL, httpTimeout, err)
+		}
+		return fmt.Errorf("request to %s timed out while connecting: %w", baseURL, err)
+	}
+	return fmt.Errorf("failed to send request: %w", err)
+}
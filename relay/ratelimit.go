@@ -0,0 +1,217 @@
+package relay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"ask_terminal/utils"
+)
+
+const (
+	// rateLimitThreshold is the remaining count at which requests start waiting for the reset
+	rateLimitThreshold = 1
+	// maxRateLimitWait caps how long a request waits proactively
+	maxRateLimitWait = 60 * time.Second
+)
+
+// RateLimitState is the last rate-limit information reported by the provider
+type RateLimitState struct {
+	LimitRequests     int       `json:"limit_requests"`
+	RemainingRequests int       `json:"remaining_requests"`
+	ResetRequests     time.Time `json:"reset_requests"`
+	LimitTokens       int       `json:"limit_tokens"`
+	RemainingTokens   int       `json:"remaining_tokens"`
+	ResetTokens       time.Time `json:"reset_tokens"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// rateLimitStatePath returns the file used to share rate-limit state between runs
+func rateLimitStatePath() string {
+	return filepath.Join(utils.DataDir(), "askta_ratelimit.json")
+}
+
+// LoadRateLimitState reads the last saved rate-limit state, or nil if none was recorded
+func LoadRateLimitState() (*RateLimitState, error) {
+	data, err := os.ReadFile(rateLimitStatePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read rate-limit state: %w", err)
+	}
+
+	var state RateLimitState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse rate-limit state: %w", err)
+	}
+	return &state, nil
+}
+
+// saveRateLimitState stores the state for the next request or run
+func saveRateLimitState(state *RateLimitState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(rateLimitStatePath(), data, 0600)
+}
+
+// recordRateLimitHeaders parses x-ratelimit-* headers from a response and saves them
+func recordRateLimitHeaders(header http.Header) {
+	state, ok := parseRateLimitHeaders(header, time.Now())
+	if !ok {
+		return
+	}
+	if err := saveRateLimitState(state); err != nil {
+		utils.LogError("Failed to save rate-limit state", err)
+	}
+}
+
+// parseRateLimitHeaders extracts rate-limit state from response headers
+func parseRateLimitHeaders(header http.Header, now time.Time) (*RateLimitState, bool) {
+	state := &RateLimitState{UpdatedAt: now}
+	found := false
+
+	if v, ok := headerInt(header, "x-ratelimit-limit-requests"); ok {
+		state.LimitRequests = v
+		found = true
+	}
+	if v, ok := headerInt(header, "x-ratelimit-remaining-requests"); ok {
+		state.RemainingRequests = v
+		found = true
+	} else {
+		state.RemainingRequests = -1
+	}
+	if v, ok := headerInt(header, "x-ratelimit-limit-tokens"); ok {
+		state.LimitTokens = v
+		found = true
+	}
+	if v, ok := headerInt(header, "x-ratelimit-remaining-tokens"); ok {
+		state.RemainingTokens = v
+		found = true
+	} else {
+		state.RemainingTokens = -1
+	}
+	state.ResetRequests = headerReset(header, "x-ratelimit-reset-requests", now)
+	state.ResetTokens = headerReset(header, "x-ratelimit-reset-tokens", now)
+
+	return state, found
+}
+
+// headerInt reads an integer header
+func headerInt(header http.Header, name string) (int, bool) {
+	value := strings.TrimSpace(header.Get(name))
+	if value == "" {
+		return 0, false
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// headerReset reads a reset header given as a duration ("6m0s"), seconds, or a unix timestamp
+func headerReset(header http.Header, name string, now time.Time) time.Time {
+	value := strings.TrimSpace(header.Get(name))
+	if value == "" {
+		return time.Time{}
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(d)
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		// Large values are absolute unix timestamps rather than delays
+		if seconds > 1e9 {
+			return time.Unix(int64(seconds), 0)
+		}
+		return now.Add(time.Duration(seconds * float64(time.Second)))
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t
+	}
+	return time.Time{}
+}
+
+// rateLimitDelay returns how long to wait before the next request given the saved state
+func rateLimitDelay(state *RateLimitState, now time.Time) time.Duration {
+	if state == nil {
+		return 0
+	}
+
+	var wait time.Duration
+	if state.RemainingRequests >= 0 && state.RemainingRequests <= rateLimitThreshold && state.ResetRequests.After(now) {
+		wait = state.ResetRequests.Sub(now)
+	}
+	if state.RemainingTokens >= 0 && state.RemainingTokens <= rateLimitThreshold && state.ResetTokens.After(now) {
+		if d := state.ResetTokens.Sub(now); d > wait {
+			wait = d
+		}
+	}
+
+	if wait > maxRateLimitWait {
+		wait = maxRateLimitWait
+	}
+	return wait
+}
+
+// waitForRateLimit delays the request when the provider reported that the limit is nearly exhausted
+func waitForRateLimit(ctx context.Context) error {
+	state, err := LoadRateLimitState()
+	if err != nil {
+		utils.LogError("Failed to load rate-limit state", err)
+		return nil
+	}
+
+	wait := rateLimitDelay(state, time.Now())
+	if wait <= 0 {
+		return nil
+	}
+
+	utils.LogInfo(fmt.Sprintf("Rate limit nearly exhausted, waiting %s before the next request", wait.Round(time.Millisecond)))
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// FormatRateLimitState renders the rate-limit state for --stats
+func FormatRateLimitState(state *RateLimitState) string {
+	if state == nil {
+		return "No rate-limit information recorded yet."
+	}
+
+	var s strings.Builder
+	now := time.Now()
+	s.WriteString(fmt.Sprintf("Rate limits (updated %s ago):\n", now.Sub(state.UpdatedAt).Round(time.Second)))
+	s.WriteString(fmt.Sprintf("  Requests: %s\n", formatRateLimitLine(state.RemainingRequests, state.LimitRequests, state.ResetRequests, now)))
+	s.WriteString(fmt.Sprintf("  Tokens:   %s\n", formatRateLimitLine(state.RemainingTokens, state.LimitTokens, state.ResetTokens, now)))
+	return s.String()
+}
+
+// formatRateLimitLine renders one remaining/limit/reset triple
+func formatRateLimitLine(remaining, limit int, reset time.Time, now time.Time) string {
+	if remaining < 0 {
+		return "not reported"
+	}
+	line := fmt.Sprintf("%d remaining", remaining)
+	if limit > 0 {
+		line = fmt.Sprintf("%d/%d remaining", remaining, limit)
+	}
+	if reset.After(now) {
+		line += fmt.Sprintf(", resets in %s", reset.Sub(now).Round(time.Second))
+	}
+	return line
+}
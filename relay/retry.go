@@ -0,0 +1,145 @@
+package relay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ask_terminal/utils"
+)
+
+const (
+	// retryBaseDelay is the wait before the first retry, doubled for each further attempt
+	retryBaseDelay = 1 * time.Second
+	// maxRetryDelay caps the wait between attempts, including Retry-After
+	maxRetryDelay = 30 * time.Second
+)
+
+// retryableStatus reports whether a response status is worth retrying
+func retryableStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryableError reports whether a transport error is a timeout worth retrying
+func retryableError(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// doWithRetry sends the request built by newRequest, retrying 429/5xx answers and
+// timeouts up to maxRetries times. newRequest is called for every attempt so the
+// body can be read again. The last response is returned as is, whatever its status.
+func doWithRetry(ctx context.Context, client *http.Client, maxRetries int, newRequest func() (*http.Request, error)) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+		// Fail fast while the provider is known to be down
+		if err := checkBreaker(req.URL.Host); err != nil {
+			return nil, err
+		}
+
+		debugf("Request: %s %s", req.Method, redactURL(req.URL))
+		debugf("Request headers: %v", redactHeaders(req.Header))
+		if debugLogging && req.GetBody != nil {
+			if body, err := req.GetBody(); err == nil {
+				data, _ := io.ReadAll(body)
+				debugf("Request body: %s", string(data))
+			}
+		}
+
+		resp, err := client.Do(req)
+		// Don't show a key sent as a query parameter in errors
+		err = redactURLError(err)
+		if err == nil {
+			recordRateLimitHeaders(resp.Header)
+		}
+		switch {
+		case err != nil && ctx.Err() == nil:
+			recordBreakerResult(req.URL.Host, err)
+		case err == nil && retryableStatus(resp.StatusCode):
+			recordBreakerResult(req.URL.Host, fmt.Errorf("status %d", resp.StatusCode))
+		case err == nil:
+			recordBreakerResult(req.URL.Host, nil)
+		}
+
+		if attempt >= maxRetries || ctx.Err() != nil {
+			return resp, err
+		}
+
+		var delay time.Duration
+		switch {
+		case err != nil:
+			if !retryableError(err) {
+				return nil, err
+			}
+			delay = backoffDelay(attempt)
+			utils.LogInfo(fmt.Sprintf("Request failed (%v), retrying in %s", err, delay.Round(time.Millisecond)))
+		case retryableStatus(resp.StatusCode):
+			delay = backoffDelay(attempt)
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+				delay = min(retryAfter, maxRetryDelay)
+			}
+			// Drain the body so the connection can be reused
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			utils.LogInfo(fmt.Sprintf("Request returned status %d, retrying in %s", resp.StatusCode, delay.Round(time.Millisecond)))
+		default:
+			return resp, nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// backoffDelay returns the exponential delay for an attempt with jitter, so that
+// clients failing together don't retry in lockstep
+func backoffDelay(attempt int) time.Duration {
+	delay := retryBaseDelay << attempt
+	if delay <= 0 || delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	// Wait between half and all of the delay
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds >= 0 {
+		return time.Duration(seconds * float64(time.Second)), true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
@@ -62,7 +62,10 @@ func DecryptAPIKey(encryptedKey string) (string, error) {
 	return decrypted, nil
 }
 
-// getOrCreateDeviceKey gets an existing key or creates and stores a new one
+// getOrCreateDeviceKey gets an existing key or creates and stores a new one. The key
+// is random rather than derived from a machine ID or hostname, so it can't be guessed
+// and doesn't change when the machine is renamed; losing the key file means entering
+// the API key again.
 func getOrCreateDeviceKey() ([]byte, error) {
 	// Get path to store the encryption key
 	homeDir, err := os.UserHomeDir()
@@ -0,0 +1,41 @@
+package security
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/zalando/go-keyring"
+)
+
+// keyringService is the service name API keys are stored under in the OS keyring
+const keyringService = "askta"
+
+// ErrKeyringUnavailable is returned when no OS secret store can be reached, typically
+// over SSH or in containers where no Secret Service (gnome-keyring, KWallet) runs
+var ErrKeyringUnavailable = errors.New("the OS keyring is not available")
+
+// StoreKeyringKey saves an API key in the OS keyring (Keychain, Secret Service or
+// Windows Credential Manager) under account
+func StoreKeyringKey(account, apiKey string) error {
+	if err := keyring.Set(keyringService, account, apiKey); err != nil {
+		return keyringError(err)
+	}
+	return nil
+}
+
+// LoadKeyringKey returns the API key saved under account, "" when there is none
+func LoadKeyringKey(account string) (string, error) {
+	apiKey, err := keyring.Get(keyringService, account)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", keyringError(err)
+	}
+	return apiKey, nil
+}
+
+// keyringError explains a keyring failure and how to work around it
+func keyringError(err error) error {
+	return fmt.Errorf("%w (%v); use key_storage: file, or unlock a keyring in this session", ErrKeyringUnavailable, err)
+}
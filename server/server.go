@@ -0,0 +1,251 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"ask_terminal/config"
+	"ask_terminal/dto"
+	"ask_terminal/relay"
+	"ask_terminal/terminal"
+	"ask_terminal/utils"
+)
+
+// defaultMaxConcurrentRequests is used when max_concurrent_requests isn't set
+const defaultMaxConcurrentRequests = 4
+
+// Server exposes the AI adapters over a small local JSON API
+type Server struct {
+	config  *config.Config
+	adapter relay.Adapter
+	slots   chan struct{} // Semaphore bounding in-flight adapter calls
+}
+
+// QueryRequest is the body accepted by /suggest and /chat
+type QueryRequest struct {
+	Query string `json:"query"`
+}
+
+// SuggestionResponse is returned by /suggest
+type SuggestionResponse struct {
+	Suggestions []utils.Suggestion `json:"suggestions"`
+}
+
+// ChatResponse is returned by /chat
+type ChatResponse struct {
+	Content string    `json:"content"`
+	Model   string    `json:"model"`
+	Usage   dto.Usage `json:"usage"`
+}
+
+// ErrorResponse is returned for failed requests
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// NewServer creates a server sharing one adapter, and so one connection pool, across requests
+func NewServer(conf *config.Config) (*Server, error) {
+	adapter, err := relay.NewAdapter(conf)
+	if err != nil {
+		return nil, err
+	}
+	maxConcurrent := conf.MaxConcurrentRequests
+	if maxConcurrent <= 0 {
+		maxConcurrent = defaultMaxConcurrentRequests
+	}
+
+	return &Server{
+		config:  conf,
+		adapter: adapter,
+		slots:   make(chan struct{}, maxConcurrent),
+	}, nil
+}
+
+// acquire waits for a free request slot, giving up when the client goes away
+func (s *Server) acquire(ctx context.Context) error {
+	select {
+	case s.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release frees a slot taken by acquire
+func (s *Server) release() {
+	<-s.slots
+}
+
+// Handler returns the HTTP routes of the API
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", s.handleHealth)
+	mux.HandleFunc("/suggest", s.handleSuggest)
+	mux.HandleFunc("/chat", s.handleChat)
+	return mux
+}
+
+// ListenAndServe serves the API on addr until SIGINT or SIGTERM. An address without
+// a host, such as ":8099", listens on the loopback interface only.
+func (s *Server) ListenAndServe(addr string) error {
+	addr = loopbackAddr(addr)
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- httpServer.ListenAndServe()
+	}()
+
+	utils.LogInfo("Serving API on " + addr)
+	fmt.Printf("ASK Terminal AI listening on %s\n", addr)
+
+	select {
+	case err := <-errChan:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+	}
+
+	// Let in-flight requests finish before exiting
+	fmt.Println("Shutting down...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down gracefully: %w", err)
+	}
+	utils.LogInfo("API server stopped")
+	return nil
+}
+
+// loopbackAddr binds addr to 127.0.0.1 when it names no host, so the API, which
+// spends the user's API key, isn't reachable from the network by default
+func loopbackAddr(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || host != "" {
+		return addr
+	}
+	return net.JoinHostPort("127.0.0.1", port)
+}
+
+// handleHealth reports that the daemon is up
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok", "model": s.config.ModelName})
+}
+
+// handleSuggest returns command suggestions for a query
+func (s *Server) handleSuggest(w http.ResponseWriter, r *http.Request) {
+	query, ok := readQuery(w, r)
+	if !ok {
+		return
+	}
+
+	utils.LogUserRequest(query, "serve/suggest")
+	if err := s.acquire(r.Context()); err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, ErrorResponse{Error: "request cancelled while waiting in queue"})
+		return
+	}
+	defer s.release()
+
+	suggestions, err := terminal.RequestCommandSuggestions(query, s.config, s.adapter)
+	if err != nil {
+		writeJSON(w, http.StatusBadGateway, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	response := SuggestionResponse{Suggestions: make([]utils.Suggestion, 0, len(suggestions))}
+	for _, suggestion := range suggestions {
+		response.Suggestions = append(response.Suggestions, suggestion.Suggestion)
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleChat answers a query in conversation mode
+func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
+	query, ok := readQuery(w, r)
+	if !ok {
+		return
+	}
+
+	utils.LogUserRequest(query, "serve/chat")
+	if err := s.acquire(r.Context()); err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, ErrorResponse{Error: "request cancelled while waiting in queue"})
+		return
+	}
+	defer s.release()
+
+	// Bounded by request_timeout, and cancelled when the client goes away
+	ctx, cancel := utils.RequestContext(s.config)
+	defer cancel()
+	stop := context.AfterFunc(r.Context(), cancel)
+	defer stop()
+
+	request := utils.BuildPrompt(query, s.config, "chat")
+	response, err := s.adapter.ChatCompletion(ctx, request)
+	if err != nil {
+		writeJSON(w, http.StatusBadGateway, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if len(response.Choices) == 0 {
+		writeJSON(w, http.StatusBadGateway, ErrorResponse{Error: terminal.ErrNoContent.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ChatResponse{
+		Content: response.Choices[0].Message.StringContent(),
+		Model:   response.Model,
+		Usage:   response.Usage,
+	})
+}
+
+// readQuery decodes the query from a POST body, writing an error response on failure
+func readQuery(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSON(w, http.StatusMethodNotAllowed, ErrorResponse{Error: "method not allowed"})
+		return "", false
+	}
+	// Browsers send cross-origin text/plain and form posts without a preflight,
+	// so only JSON bodies are accepted
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+		writeJSON(w, http.StatusUnsupportedMediaType, ErrorResponse{Error: "Content-Type must be application/json"})
+		return "", false
+	}
+
+	var body QueryRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid JSON body: " + err.Error()})
+		return "", false
+	}
+	if body.Query == "" {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "query is required"})
+		return "", false
+	}
+	return body.Query, true
+}
+
+// writeJSON writes v as a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		utils.LogError("Failed to write API response", err)
+	}
+}
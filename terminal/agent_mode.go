@@ -0,0 +1,165 @@
+package terminal
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"ask_terminal/common"
+	"ask_terminal/config"
+	"ask_terminal/dto"
+	"ask_terminal/relay"
+	"ask_terminal/utils"
+
+	"github.com/fatih/color"
+	"golang.org/x/term"
+)
+
+// runShellTool is the one tool offered in agent mode
+var runShellTool = dto.Tool{
+	Type: "function",
+	Function: dto.FunctionDefinition{
+		Name:        "run_shell_command",
+		Description: "Run a command line in the user's shell and return its combined output and exit status. The user confirms every command first.",
+		Parameters: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"command": map[string]any{"type": "string", "description": "The command line to run"},
+			},
+			"required": []string{"command"},
+		},
+	},
+}
+
+// maxToolOutput is how much of a command's output is sent back to the model
+const maxToolOutput = 16 << 10
+
+// StartAgentMode lets the AI investigate the query by running commands, each
+// confirmed by the user, until it can answer
+func StartAgentMode(query string, conf *config.Config) {
+	if query == "" {
+		fmt.Println("Error: --agent requires a query describing the problem")
+		os.Exit(1)
+	}
+	// Every command is confirmed on the terminal, never run unattended
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		fmt.Println("Error: --agent asks before running each command and needs a terminal")
+		os.Exit(1)
+	}
+
+	utils.LogUserRequest(query, "agent")
+
+	adapter, err := relay.NewAdapter(conf)
+	if err != nil {
+		fmt.Printf("Error initializing AI adapter: %v\n", err)
+		utils.LogError("Error initializing AI adapter", err)
+		os.Exit(common.ExitConfig)
+	}
+	if !adapter.Capabilities().Tools {
+		fmt.Printf("Error: provider %s does not support tool calls, which --agent needs\n", conf.Provider)
+		os.Exit(common.ExitConfig)
+	}
+
+	request := utils.BuildPrompt(query, conf, "agent")
+	request.Tools = []dto.Tool{runShellTool}
+
+	answer, err := runAgent(adapter, conf, request, bufio.NewReader(os.Stdin))
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		utils.LogError("Agent mode failed", err)
+		os.Exit(ExitCodeFor(err))
+	}
+	utils.LogSystemResponse(len(answer), true, answer)
+
+	rendered, err := renderMarkdown(answer)
+	if err != nil {
+		rendered = answer
+	}
+	fmt.Println(rendered)
+}
+
+// runAgent sends the conversation, runs the commands the model asks for and sends
+// their output back, until the model answers without calling the tool or
+// agent_max_steps turns have passed
+func runAgent(adapter relay.Adapter, conf *config.Config, request *dto.GeneralOpenAIRequest, input *bufio.Reader) (string, error) {
+	steps := conf.AgentStepLimit()
+	for step := 0; step < steps; step++ {
+		printStatus(conf, "Thinking...")
+
+		ctx, cancel := utils.RequestContext(conf)
+		response, err := adapter.ChatCompletion(ctx, request)
+		cancel()
+		if err != nil {
+			return "", err
+		}
+		if len(response.Choices) == 0 {
+			return responseContent(response)
+		}
+
+		message := response.Choices[0].Message
+		calls, err := message.ParseToolCalls()
+		if err != nil {
+			return "", fmt.Errorf("failed to parse tool calls: %w", err)
+		}
+		if len(calls) == 0 {
+			return responseContent(response)
+		}
+
+		// Text next to tool calls is the model explaining its next step
+		if text := strings.TrimSpace(message.StringContent()); text != "" {
+			fmt.Println(color.HiBlackString(text))
+		}
+
+		// Each call must be answered by a tool message after the assistant turn
+		request.Messages = append(request.Messages, message)
+		for _, call := range calls {
+			result := dto.Message{Role: "tool", ToolCallId: call.ID}
+			result.SetStringContent(runToolCall(call, conf, input))
+			request.Messages = append(request.Messages, result)
+		}
+	}
+	return "", fmt.Errorf("no answer after %d steps (agent_max_steps)", steps)
+}
+
+// runToolCall runs the command of one run_shell_command call after the user
+// confirms it, and returns what to tell the model
+func runToolCall(call dto.ToolCallRequest, conf *config.Config, input *bufio.Reader) string {
+	if call.Function.Name != runShellTool.Function.Name {
+		return fmt.Sprintf("Unknown tool %q, only %s is available.", call.Function.Name, runShellTool.Function.Name)
+	}
+	var args struct {
+		Command string `json:"command"`
+	}
+	if err := call.Function.ParseArguments(&args); err != nil || strings.TrimSpace(args.Command) == "" {
+		return `Invalid arguments, expected {"command": "..."}.`
+	}
+
+	fmt.Println(color.CyanString("\n$ %s", args.Command))
+	if dangerous, reason := utils.CheckDangerousCommand(args.Command, conf); dangerous {
+		fmt.Println(color.RedString("Warning: %s", reason))
+	}
+	fmt.Print("Run this command? [y/N] ")
+	answer, _ := input.ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	if answer != "y" && answer != "yes" {
+		utils.LogInfo("Agent command declined: " + args.Command)
+		return "The user declined to run this command."
+	}
+
+	utils.LogInfo("Agent command: " + args.Command)
+	output, err := utils.ExecuteCommand(args.Command)
+	fmt.Print(output)
+	if output != "" && !strings.HasSuffix(output, "\n") {
+		fmt.Println()
+	}
+
+	status := "exit status 0"
+	if err != nil {
+		status = err.Error()
+	}
+	if len(output) > maxToolOutput {
+		output = output[:maxToolOutput] + fmt.Sprintf("\n[output truncated, %d bytes total]", len(output))
+	}
+	return output + "\n[" + status + "]"
+}
@@ -1,35 +1,49 @@
 package terminal
 
 import (
+	"ask_terminal/common"
 	"ask_terminal/config"
 	"ask_terminal/dto"
 	"ask_terminal/service"
 	"ask_terminal/utils"
+	"bufio"
 	"bytes"
 	"context"
 	"fmt"
 	"os"
+	"os/exec"
+	"runtime"
 	"strings"
-	"time"
 
 	"ask_terminal/relay"
 
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/charmbracelet/glamour"
 	"github.com/charmbracelet/lipgloss"
+	"golang.org/x/term"
 )
 
 // ChatModel represents the state for conversation mode
 type ChatModel struct {
 	query     string
 	content   string
+	answer    string // Unformatted answer, code blocks are copied from it
+	notice    string // Result of the last copy
 	viewport  viewport.Model
 	isLoading bool
 	config    *config.Config
 	err       error
+
+	stream      *chatStream // Answer being received, nil once it is complete
+	received    int         // Chunks received so far
+	rendered    string      // Markdown rendering of answer[:renderedLen], shown while streaming
+	renderedLen int
 }
 
+// streamRenderChunks is how often, in chunks, a streaming answer is re-rendered as
+// markdown; text received in between is shown raw below the last rendering
+const streamRenderChunks = 20
+
 // NewChatModel creates the initial state for chat mode
 func NewChatModel(query string, conf *config.Config) ChatModel {
 	// Configure viewport for scrollable content
@@ -54,35 +68,126 @@ func (m ChatModel) Init() tea.Cmd {
 	return fetchAIResponse(m.query, m.config)
 }
 
-// ChatResponseMsg represents a message with AI response content
+// ChatResponseMsg carries the text of the answer received since the previous
+// one. The first message of a streamed answer only carries the stream, the last
+// one has done set, with any text that follows the answer such as citations.
 type ChatResponseMsg struct {
 	content string
 	err     error
+	done    bool
+	stream  *chatStream
 }
 
-// fetchAIResponse sends a request to the AI service and returns the response
+// chatStream is an answer streaming into the viewport. Only the command reading
+// the next chunk touches it, one at a time.
+type chatStream struct {
+	chunks      chan *dto.ChatCompletionsStreamResponse
+	cancel      context.CancelFunc
+	query       string
+	conf        *config.Config
+	answer      strings.Builder
+	annotations []dto.Annotation
+	citations   []string
+	filtered    *ContentFilteredError
+}
+
+// fetchAIResponse sends the query to the AI service and starts streaming the answer
 func fetchAIResponse(query string, conf *config.Config) tea.Cmd {
 	return func() tea.Msg {
 		// Get the appropriate adapter
 		adapter, err := relay.NewAdapter(conf)
 		if err != nil {
-			return ChatResponseMsg{"Error initializing AI adapter: " + err.Error(), err}
+			utils.LogError("Error initializing AI adapter", err)
+			return ChatResponseMsg{err: err, done: true}
 		}
 
 		// Build request using the utils package
 		request := utils.BuildPrompt(query, conf, "chat")
-		// Execute request
-		ctx := context.Background()
-		response, err := adapter.ChatCompletion(ctx, request)
+		ctx, cancel := utils.RequestContext(conf)
+		chunks, err := adapter.ChatCompletionStream(ctx, request)
 		if err != nil {
-			return ChatResponseMsg{"Error communicating with AI: " + err.Error(), err}
+			cancel()
+			if content, notice, ok := cachedFallback(conf, "chat", query, err); ok {
+				return ChatResponseMsg{content: notice + "\n\n" + content, done: true}
+			}
+			return ChatResponseMsg{err: err, done: true}
 		}
 
-		if len(response.Choices) == 0 {
-			return ChatResponseMsg{"No response content received from AI.", nil}
+		return ChatResponseMsg{stream: &chatStream{chunks: chunks, cancel: cancel, query: query, conf: conf}}
+	}
+}
+
+// next waits for the next piece of text, or the end of the answer
+func (s *chatStream) next() tea.Cmd {
+	return func() tea.Msg {
+		for response := range s.chunks {
+			s.citations = append(s.citations, response.Citations...)
+			if len(response.Choices) == 0 {
+				continue
+			}
+			choice := response.Choices[0]
+			s.annotations = append(s.annotations, choice.Delta.Annotations...)
+			if refusal := choice.Delta.GetRefusal(); refusal != "" {
+				if s.filtered == nil {
+					s.filtered = &ContentFilteredError{}
+				}
+				s.filtered.Reason += refusal
+			}
+			if choice.FinishReason != nil && *choice.FinishReason == dto.FinishReasonContentFilter && s.filtered == nil {
+				s.filtered = &ContentFilteredError{}
+			}
+			if choice.Delta.Content != nil && *choice.Delta.Content != "" {
+				s.answer.WriteString(*choice.Delta.Content)
+				return ChatResponseMsg{content: *choice.Delta.Content}
+			}
 		}
+		return s.finish()
+	}
+}
+
+// finish reports the end of the answer, caching it for offline use
+func (s *chatStream) finish() ChatResponseMsg {
+	s.cancel()
+	if s.filtered != nil {
+		return ChatResponseMsg{err: s.filtered, done: true}
+	}
+	if s.answer.Len() == 0 {
+		return ChatResponseMsg{err: ErrNoContent, done: true}
+	}
+	cacheResponse(s.conf, "chat", s.query, s.answer.String())
 
-		return ChatResponseMsg{response.Choices[0].Message.StringContent(), nil}
+	msg := ChatResponseMsg{done: true}
+	if s.conf.Citations {
+		msg.content = "\n" + RenderCitations(dto.CollectCitations(s.annotations, s.citations))
+	}
+	return msg
+}
+
+// chatFormatter returns the formatter for the output setting, glamour when it's unset
+func chatFormatter(conf *config.Config) Formatter {
+	if formatter, err := NewFormatter(conf.Output); err == nil {
+		return formatter
+	}
+	return GlamourFormatter{}
+}
+
+// renderPartial re-renders the answer received so far as markdown. Other output
+// formats only apply to the whole answer, so until then it is shown raw.
+func (m *ChatModel) renderPartial() {
+	if _, ok := chatFormatter(m.config).(GlamourFormatter); !ok {
+		return
+	}
+	m.rendered = GlamourFormatter{}.Format(m.answer)
+	m.renderedLen = len(m.answer)
+}
+
+// setContent replaces the viewport content, following the end of the answer
+// unless the user scrolled up
+func (m *ChatModel) setContent(content string) {
+	follow := m.viewport.AtBottom()
+	m.viewport.SetContent(content)
+	if follow {
+		m.viewport.GotoBottom()
 	}
 }
 
@@ -94,13 +199,50 @@ func (m ChatModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "q", "ctrl+c", "esc":
+			if m.stream != nil {
+				m.stream.cancel()
+			}
 			return m, tea.Quit
+		case "r":
+			// Retry the query after a failed request
+			if m.err != nil && !m.isLoading {
+				utils.LogInfo("Retrying conversation query: " + m.query)
+				m.err = nil
+				m.isLoading = true
+				m.content = "Loading response..."
+				m.answer, m.notice = "", ""
+				m.received, m.rendered, m.renderedLen = 0, "", 0
+				return m, fetchAIResponse(m.query, m.config)
+			}
+		case "o", "e":
+			// Read the answer in the pager, or edit a copy of it, with the TUI suspended
+			if !m.isLoading && m.err == nil {
+				cmd, err := openAnswerCmd(m.answer, msg.String() == "e")
+				if err != nil {
+					m.notice = "Error: " + err.Error()
+					return m, nil
+				}
+				return m, cmd
+			}
+		case "1", "2", "3", "4", "5", "6", "7", "8", "9":
+			// Copy the numbered code block
+			if !m.isLoading && m.err == nil {
+				n := int(msg.String()[0] - '0')
+				m.notice = copyCodeBlock(m.answer, n)
+				return m, nil
+			}
 		}
 
 		// Handle viewport scrolling
 		m.viewport, cmd = m.viewport.Update(msg)
 		return m, cmd
 
+	case answerClosedMsg:
+		if msg.err != nil {
+			m.notice = "Error: " + msg.err.Error()
+		}
+		return m, nil
+
 	case tea.WindowSizeMsg:
 		// Adjust viewport size when window is resized
 		m.viewport.Width = msg.Width - 4
@@ -108,18 +250,39 @@ func (m ChatModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case ChatResponseMsg:
+		if msg.stream != nil {
+			m.stream = msg.stream
+			return m, m.stream.next()
+		}
+		if !msg.done {
+			// Show the text as it arrives, re-rendering the markdown every few chunks
+			m.answer += msg.content
+			m.received++
+			if m.received%streamRenderChunks == 0 {
+				m.renderPartial()
+			}
+			m.setContent(m.rendered + m.answer[m.renderedLen:])
+			return m, m.stream.next()
+		}
+
 		m.isLoading = false
+		m.stream = nil
 		if msg.err != nil {
 			m.err = msg.err
 			m.content = fmt.Sprintf("Error: %v", msg.err)
 			utils.LogSystemResponse(0, false, m.content)
 		} else {
-			m.content = msg.content
+			m.answer += msg.content
+			m.content = m.answer
 			utils.LogSystemResponse(len(m.content), true, m.content)
+			if m.config.CodeBlockIndex {
+				m.content = numberCodeBlocks(m.content)
+			}
+			m.content = chatFormatter(m.config).Format(m.content)
 		}
 
-		// Set content in viewport for scrolling
-		m.viewport.SetContent(m.content)
+		// Set the final rendering in the viewport for scrolling
+		m.setContent(m.content)
 		return m, nil
 	}
 
@@ -136,96 +299,329 @@ func (m ChatModel) View() string {
 	// Query display using shared function
 	s.WriteString(RenderQueryInfo(m.query))
 
-	if m.isLoading {
+	if m.isLoading && m.answer == "" {
 		s.WriteString("Loading response...\n")
+	} else if m.isLoading {
+		// The answer so far, scrollable while the rest arrives
+		s.WriteString(m.viewport.View() + "\n\n")
+		s.WriteString(RenderHelpText("Receiving response... • ↑/↓ to scroll • q to exit\n"))
 	} else if m.err != nil {
 		// Error display using shared function
 		s.WriteString(RenderError(m.err))
+		s.WriteString(RenderHelpText("Press r to retry • q to exit\n"))
 	} else {
 		// Content display in viewport
 		s.WriteString(m.viewport.View() + "\n\n")
 
+		if m.notice != "" {
+			s.WriteString(m.notice + "\n")
+		}
+
 		// Help text using shared function
-		s.WriteString(RenderHelpText("Press q to exit • ↑/↓ to scroll\n"))
+		if len(codeBlocks(m.answer)) > 0 {
+			s.WriteString(RenderHelpText("Press q to exit • ↑/↓ to scroll • o pager • e editor • 1-9 to copy a code block\n"))
+		} else {
+			s.WriteString(RenderHelpText("Press q to exit • ↑/↓ to scroll • o pager • e editor\n"))
+		}
 	}
 
 	return s.String()
 }
 
-// StartConversationMode starts conversation mode with an initial query
+// answerClosedMsg reports that the pager or editor showing an answer exited
+type answerClosedMsg struct {
+	err error
+}
+
+// openAnswerCmd writes an answer to a temporary file and opens it in $PAGER, or in
+// $EDITOR when edit is set, suspending the TUI until the program exits
+func openAnswerCmd(answer string, edit bool) (tea.Cmd, error) {
+	f, err := os.CreateTemp("", "askta_answer_*.md")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	path := f.Name()
+	_, err = f.WriteString(answer)
+	f.Close()
+	if err != nil {
+		os.Remove(path)
+		return nil, fmt.Errorf("failed to write temporary file: %w", err)
+	}
+
+	// The variables may hold arguments, as in EDITOR="code -w"
+	program := answerViewer(edit)
+	args := append(strings.Fields(program)[1:], path)
+	cmd := exec.Command(strings.Fields(program)[0], args...)
+
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		os.Remove(path)
+		return answerClosedMsg{err: err}
+	}), nil
+}
+
+// answerViewer returns the pager or editor from the environment, or the platform default
+func answerViewer(edit bool) string {
+	name, fallback := "PAGER", "less"
+	if edit {
+		name, fallback = "EDITOR", "vi"
+	}
+	if program := strings.TrimSpace(os.Getenv(name)); program != "" {
+		return program
+	}
+	if runtime.GOOS == "windows" {
+		if edit {
+			return "notepad"
+		}
+		return "more"
+	}
+	return fallback
+}
+
+// copyCodeBlock copies the nth code block of an answer and describes the outcome
+func copyCodeBlock(answer string, n int) string {
+	block, err := CodeBlock(answer, n)
+	if err == nil {
+		err = utils.CopyToClipboard(block)
+	}
+	if err != nil {
+		return "Error: " + err.Error()
+	}
+	return fmt.Sprintf("Copied code block %d to clipboard (%d chars).", n, len(block))
+}
+
+// StartConversationMode starts conversation mode with an initial query.
+// With --follow it keeps reading follow-up queries, reusing the adapter, until EOF or quit.
 func StartConversationMode(query string, conf *config.Config) {
+	var reader *bufio.Reader
+	if conf.Follow {
+		reader = bufio.NewReader(os.Stdin)
+		if strings.TrimSpace(query) == "" {
+			var ok bool
+			if query, ok = readFollowUpQuery(reader); !ok {
+				return
+			}
+		}
+	}
+
+	if strings.TrimSpace(query) == "" {
+		fmt.Printf("Error: %v\n", ErrEmptyQuery)
+		os.Exit(common.ExitGeneric)
+	}
+
 	utils.LogInfo(fmt.Sprintf("Starting Chat Mode with query: %s", query))
 	// Get the appropriate adapter
 	adapter, err := relay.NewAdapter(conf)
 	if err != nil {
 		fmt.Printf("Error initializing AI adapter: %v\n", err)
 		utils.LogError("Error initializing AI adapter", err)
-		os.Exit(1)
+		os.Exit(common.ExitConfig)
 	}
 
+	// An explicit --output prints the whole answer once through its formatter
+	var formatter Formatter
+	if conf.Output != "" {
+		formatter, err = NewFormatter(conf.Output)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(common.ExitConfig)
+		}
+	}
+	// --diff shows what changed from the answer being revised instead
+	if conf.Diff {
+		formatter = DiffFormatter{Previous: conf.AssistantDraft}
+	}
+
+	for {
+		err := answerConversationQuery(query, conf, adapter, formatter)
+		if !conf.Follow {
+			if err != nil {
+				os.Exit(ExitCodeFor(err))
+			}
+			return
+		}
+
+		// A failed answer doesn't end a follow session, the error is already printed
+		var ok bool
+		if query, ok = readFollowUpQuery(reader); !ok {
+			return
+		}
+		utils.LogInfo(fmt.Sprintf("Chat Mode follow-up query: %s", query))
+	}
+}
+
+// readFollowUpQuery prompts for another query, returning false on EOF or quit
+func readFollowUpQuery(reader *bufio.Reader) (string, bool) {
+	for {
+		fmt.Print("\nAsk another (quit or Ctrl+D to exit): ")
+		line, err := reader.ReadString('\n')
+		query := strings.TrimSpace(line)
+		switch {
+		case query == "quit" || query == "exit" || query == "q":
+			return "", false
+		case query != "":
+			return query, true
+		case err != nil:
+			fmt.Println()
+			return "", false
+		}
+	}
+}
+
+// answerConversationQuery sends one chat query and prints the answer. Errors are
+// printed before being returned so the caller only has to pick an exit code.
+func answerConversationQuery(query string, conf *config.Config, adapter relay.Adapter, formatter Formatter) error {
 	// Build request using the utils package
 	request := utils.BuildPrompt(query, conf, "chat")
 
 	// Execute request
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	ctx, cancel := utils.RequestContext(conf)
 	defer cancel()
 
 	// Print a "thinking" message
-	fmt.Println("Processing your request...")
+	if formatter == nil {
+		printStatus(conf, "Processing your request...")
+	}
 
 	// Use streaming response by default
 	stream, err := adapter.ChatCompletionStream(ctx, request)
 	if err != nil {
+		if content, notice, ok := cachedFallback(conf, "chat", query, err); ok {
+			printCachedAnswer(conf, formatter, content, notice)
+			return nil
+		}
 		fmt.Printf("Error communicating with AI: %v\n", err)
 		utils.LogError("Error communicating with AI", err)
-		os.Exit(1)
-	}
-
-	// Initialize markdown renderer
-	renderer, err := glamour.NewTermRenderer(
-		glamour.WithAutoStyle(),
-		glamour.WithWordWrap(100),
-	)
-	if err != nil {
-		// Fall back to plain text if renderer can't be created
-		fmt.Println("\nResponse:")
-		for response := range stream {
-			if len(response.Choices) > 0 && response.Choices[0].Delta.Content != nil {
-				fmt.Print(*response.Choices[0].Delta.Content)
-				os.Stdout.Sync()
-			}
-		}
-		fmt.Println()
-		return
+		return err
 	}
 
 	// Create buffer to collect content
 	var buffer bytes.Buffer
 
 	// Process response
-	fmt.Println("\nResponse:")
+	if formatter == nil {
+		printStatus(conf, "\nResponse:")
+	}
 
-	// Simple streaming output instead of trying to clear the screen
+	// Simple streaming output instead of trying to clear the screen,
+	// unless paragraphs should be rendered as they complete
+	var incremental *incrementalRenderer
+	if formatter == nil && conf.IncrementalRender && term.IsTerminal(int(os.Stdout.Fd())) {
+		incremental = &incrementalRenderer{}
+	}
+
+	var filtered *ContentFilteredError
+	var annotations []dto.Annotation
+	var citationURLs []string
+	var reported *dto.Usage
 	for response := range stream {
-		if len(response.Choices) > 0 && response.Choices[0].Delta.Content != nil {
-			content := *response.Choices[0].Delta.Content
+		citationURLs = append(citationURLs, response.Citations...)
+		if response.Usage != nil {
+			reported = response.Usage
+		}
+		if len(response.Choices) == 0 {
+			continue
+		}
+		choice := response.Choices[0]
+		annotations = append(annotations, choice.Delta.Annotations...)
+		if refusal := choice.Delta.GetRefusal(); refusal != "" {
+			if filtered == nil {
+				filtered = &ContentFilteredError{}
+			}
+			filtered.Reason += refusal
+		}
+		if choice.FinishReason != nil && *choice.FinishReason == dto.FinishReasonContentFilter && filtered == nil {
+			filtered = &ContentFilteredError{}
+		}
+		if choice.Delta.Content != nil {
+			content := *choice.Delta.Content
 			buffer.WriteString(content)
+			if formatter != nil {
+				continue
+			}
+			if incremental != nil {
+				incremental.Write(content)
+				continue
+			}
 			fmt.Print(content)
 			os.Stdout.Sync()
 		}
 	}
 
-	// Final render with markdown formatting
-	fmt.Println("\n\n--- Formatted Response ---")
-	rendered, _ := renderer.Render(buffer.String())
+	// Explain filtered answers instead of showing an empty response
+	if filtered != nil {
+		fmt.Println()
+		fmt.Print(RenderError(filtered))
+		utils.LogInfo(fmt.Sprintf("Chat Mode request was filtered: %v", filtered))
+		return filtered
+	}
+	if buffer.Len() == 0 && conf.RetryOnEmpty {
+		if content := retryWithoutStream(ctx, adapter, request); content != "" {
+			buffer.WriteString(content)
+			if formatter == nil {
+				if incremental != nil {
+					incremental.Write(content)
+				} else {
+					fmt.Print(content)
+				}
+			}
+		}
+	}
+	if buffer.Len() == 0 {
+		fmt.Print(RenderError(ErrNoContent))
+		return ErrNoContent
+	}
+	cacheResponse(conf, "chat", query, buffer.String())
+
+	// Deferred calls run last first: citations, then the usage footer
+	defer printUsage(conf, utils.UsageFor(request.Model, reported, request, buffer.String()))
+
+	// List the answer's sources after it
+	if conf.Citations {
+		defer fmt.Print(RenderCitations(dto.CollectCitations(annotations, citationURLs)))
+	}
+
+	if formatter != nil {
+		fmt.Print(formatter.Format(buffer.String()))
+		utils.LogSystemResponse(buffer.Len(), true, buffer.String())
+		utils.LogInfo(fmt.Sprintf("End of Chat Mode with answer: %s", buffer.String()))
+		return nil
+	}
+
+	// Paragraphs were rendered while streaming, only the last one is left
+	if incremental != nil {
+		incremental.Flush()
+		utils.LogSystemResponse(buffer.Len(), true, buffer.String())
+		utils.LogInfo(fmt.Sprintf("End of Chat Mode with answer: %s", buffer.String()))
+		return nil
+	}
+
+	// Final render with markdown formatting, sized to the terminal and any tables.
+	// The width is measured now, after streaming, so resizes during the stream are honored.
+	answer := buffer.String()
+	if conf.CodeBlockIndex {
+		answer = numberCodeBlocks(answer)
+	}
+	rendered, err := renderMarkdown(answer)
+	if err != nil {
+		// Fall back to the plain text already printed
+		fmt.Println()
+		utils.LogSystemResponse(buffer.Len(), true, buffer.String())
+		utils.LogInfo(fmt.Sprintf("End of Chat Mode with answer: %s", buffer.String()))
+		return nil
+	}
+	fmt.Println()
+	printStatus(conf, "\n--- Formatted Response ---")
 	fmt.Println(rendered)
+	utils.LogSystemResponse(buffer.Len(), true, buffer.String())
 	utils.LogInfo(fmt.Sprintf("End of Chat Mode with answer: %s", rendered))
+	return nil
 }
 
 // ChatMode handles conversations with AI
 type ChatMode struct {
 	aiService *service.AIService
 	model     string
+	formatter Formatter
 }
 
 // NewChatMode creates a new chat mode with the given AI service
@@ -233,12 +629,22 @@ func NewChatMode(aiService *service.AIService, model string) *ChatMode {
 	return &ChatMode{
 		aiService: aiService,
 		model:     model,
+		formatter: GlamourFormatter{},
 	}
 }
 
+// SetFormatter changes how answers are printed
+func (c *ChatMode) SetFormatter(formatter Formatter) {
+	c.formatter = formatter
+}
+
 // ProcessQuery sends a query to the AI service and prints the response
 // Stream is now true by default
 func (c *ChatMode) ProcessQuery(query string, systemPrompt string, stream ...bool) error {
+	if strings.TrimSpace(query) == "" {
+		return ErrEmptyQuery
+	}
+
 	messages := []dto.Message{
 		{
 			Role:    "system",
@@ -266,12 +672,7 @@ func (c *ChatMode) ProcessQuery(query string, systemPrompt string, stream ...boo
 
 // renderMarkdown converts Markdown content to terminal-friendly styled text
 func renderMarkdown(markdown string) (string, error) {
-	renderer, err := glamour.NewTermRenderer(
-		// Use the default dark style
-		glamour.WithAutoStyle(),
-		// Ensure compatibility with standard terminals
-		glamour.WithWordWrap(80),
-	)
+	renderer, err := newMarkdownRenderer(markdown)
 	if err != nil {
 		return markdown, err
 	}
@@ -289,18 +690,12 @@ func (c *ChatMode) handleNonStreamingResponse(ctx context.Context, messages []dt
 		return err
 	}
 
-	if len(response.Choices) > 0 {
-		content := response.Choices[0].Message.StringContent()
-
-		// Render markdown to terminal-friendly output
-		rendered, err := renderMarkdown(content)
-		if err != nil {
-			// If rendering fails, fall back to plain content
-			fmt.Print(content)
-		} else {
-			fmt.Print(rendered)
-		}
+	content, err := responseContent(response)
+	if err != nil {
+		return err
 	}
+
+	fmt.Print(c.formatter.Format(content))
 	return nil
 }
 
@@ -328,10 +723,9 @@ func (c *ChatMode) handleStreamingResponse(ctx context.Context, messages []dto.M
 		}
 	}
 
-	// Final render with markdown formatting
+	// Final render through the formatter
 	fmt.Println("\n\n--- Formatted Response ---")
-	rendered, _ := renderMarkdown(buffer.String())
-	fmt.Println(rendered)
+	fmt.Println(c.formatter.Format(buffer.String()))
 
 	return nil
 }
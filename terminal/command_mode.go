@@ -1,38 +1,43 @@
 package terminal
 
 import (
+	"ask_terminal/common"
 	"ask_terminal/config"
 	"ask_terminal/dto"
 	"ask_terminal/relay"
-	"ask_terminal/service"
 	"ask_terminal/utils"
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"strings"
 	"time"
 
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/fatih/color"
 )
 
-// CommandSuggestion represents a command with its description
+// CommandSuggestion is a suggestion with the virtual terminal's editing state
 type CommandSuggestion struct {
-	Command        string // The original command
-	EditedCommand  string // The edited version of the command
-	Description    string
-	CursorPosition int // Track cursor position for each command
+	utils.Suggestion        // The original command and its description
+	EditedCommand    string // The edited version of the command
+	CursorPosition   int    // Track cursor position for each command
+	Notice           string // Set when the suggestion was served from the offline cache
 }
 
 // VirtualTerminalModel represents the model for the virtual terminal
 type VirtualTerminalModel struct {
 	query             string
 	input             textinput.Model
+	multilineInput    textarea.Model // query input used while multiline is on
+	multiline         bool           // true when queries are typed in the multi-line textarea
 	suggestions       []CommandSuggestion
 	selected          int
 	loading           bool
@@ -43,36 +48,83 @@ type VirtualTerminalModel struct {
 	config            *config.Config
 	logger            *utils.Logger
 	adapter           relay.AIAdapter
-	commandResult     string // stores the result of executed commands
-	showResult        bool   // whether to show command result
+	commandResult     string            // stores the result of executed commands
+	showResult        bool              // whether to show command result
+	confirming        bool              // true while a dangerous command waits for confirmation
+	pendingCommand    string            // dangerous command waiting for confirmation
+	pendingReason     string            // why the pending command is considered dangerous
+	explanations      map[string]string // cached AI explanations of dangerous commands
+	pendingBatch      []string          // pinned commands waiting for confirmation as a batch
+	pendingAttached   bool              // run the pending command with the TUI suspended
+	pinned            []string          // commands pinned across queries
+	pinnedView        bool              // true while the pinned commands view is shown
+	pinnedSelected    int               // selected entry in the pinned commands view
+	width             int               // terminal width reported by the last resize
+	streaming         bool              // true while suggestions are still arriving
+	requestID         int               // identifies the current suggestion request, so stale answers are dropped
+	lastExitCode      int               // exit code of the last executed command, returned when quitting
+	running           bool              // true while an executed command is in flight
+	runningCommand    string            // command shown next to the spinner
+	runStarted        time.Time         // when the running command started
+	spinner           spinner.Model     // progress indicator for running commands
+	spinnerEnabled    bool              // false when the spinner config is "none"
+	copyStatus        string            // result of the last Ctrl+y copy, cleared by the next key
+	usage             string            // token usage of the last answer, shown with show_usage
+	queryHistory      []string          // past queries, oldest first, recalled with up/down in query mode
+	historyIndex      int               // recalled entry in queryHistory; len(queryHistory) is the current draft
+	historyDraft      string            // what was typed before recalling history
 }
 
+// maxQueryHistory caps the queries kept for up/down recall
+const maxQueryHistory = 200
+
 // NewVirtualTerminalModel creates a new virtual terminal model
 func NewVirtualTerminalModel(conf *config.Config) *VirtualTerminalModel {
 	// Initialize text input
 	ti := textinput.New()
 	ti.Placeholder = "Type your command query here..."
 	ti.Focus()
-	ti.CharLimit = 256
+	ti.CharLimit = conf.InputCharLimit
 	ti.Width = 80
 
+	// Initialize the multi-line query input, where Enter inserts a newline
+	ta := textarea.New()
+	ta.Placeholder = "Type or paste a multi-line query..."
+	ta.CharLimit = conf.InputCharLimit
+	ta.ShowLineNumbers = false
+	ta.SetWidth(80)
+	ta.SetHeight(5)
+
+	// Initialize the spinner shown while commands run
+	sp, spinnerEnabled := newExecutionSpinner(conf.Spinner)
+
 	// Initialize logger
 	logger := utils.NewLogger()
+	history := loadQueryHistory(logger)
 
 	// Create AI adapter
 	adapter, err := relay.NewAdapter(conf)
 	if err != nil {
 		return &VirtualTerminalModel{
-			input:     ti,
-			err:       err,
-			config:    conf,
-			logger:    logger,
-			queryMode: true,
+			input:          ti,
+			multilineInput: ta,
+			spinner:        sp,
+			spinnerEnabled: spinnerEnabled,
+			err:            err,
+			config:         conf,
+			logger:         logger,
+			queryMode:      true,
+			explanations:   make(map[string]string),
+			queryHistory:   history,
+			historyIndex:   len(history),
 		}
 	}
 
 	return &VirtualTerminalModel{
 		input:             ti,
+		multilineInput:    ta,
+		spinner:           sp,
+		spinnerEnabled:    spinnerEnabled,
 		config:            conf,
 		logger:            logger,
 		adapter:           adapter,
@@ -80,7 +132,63 @@ func NewVirtualTerminalModel(conf *config.Config) *VirtualTerminalModel {
 		directCommandMode: false,
 		cursorVisible:     true,
 		showResult:        false,
+		explanations:      make(map[string]string),
+		queryHistory:      history,
+		historyIndex:      len(history),
+	}
+}
+
+// loadQueryHistory reads past queries from the command history, oldest first,
+// dropping consecutive duplicates
+func loadQueryHistory(logger *utils.Logger) []string {
+	items, err := logger.GetRecentCommands(maxQueryHistory)
+	if err != nil {
+		utils.LogError("Failed to load query history", err)
+		return nil
+	}
+
+	var history []string
+	for i := len(items) - 1; i >= 0; i-- {
+		history = appendQueryHistory(history, items[i].Query)
+	}
+	return history
+}
+
+// appendQueryHistory adds a query unless it repeats the last one, keeping at most maxQueryHistory
+func appendQueryHistory(history []string, query string) []string {
+	query = strings.TrimSpace(query)
+	if query == "" || (len(history) > 0 && history[len(history)-1] == query) {
+		return history
+	}
+	history = append(history, query)
+	if len(history) > maxQueryHistory {
+		history = history[len(history)-maxQueryHistory:]
+	}
+	return history
+}
+
+// recallQuery puts an older or newer past query into the input, like shell history;
+// moving past the newest entry restores what was being typed
+func (m *VirtualTerminalModel) recallQuery(older bool) {
+	if len(m.queryHistory) == 0 {
+		return
+	}
+	if m.historyIndex == len(m.queryHistory) {
+		m.historyDraft = m.input.Value()
+	}
+
+	if older && m.historyIndex > 0 {
+		m.historyIndex--
+	} else if !older && m.historyIndex < len(m.queryHistory) {
+		m.historyIndex++
+	}
+
+	if m.historyIndex == len(m.queryHistory) {
+		m.input.SetValue(m.historyDraft)
+	} else {
+		m.input.SetValue(m.queryHistory[m.historyIndex])
 	}
+	m.input.CursorEnd()
 }
 
 // Init initializes the model
@@ -97,11 +205,119 @@ func (m VirtualTerminalModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		m.copyStatus = ""
+
+		// Only quitting is possible while a command runs
+		if m.running {
+			switch msg.String() {
+			case "ctrl+c", "ctrl+d", "ctrl+z", "ctrl+q":
+				return m, tea.Quit
+			}
+			return m, nil
+		}
+
+		// A dangerous command is waiting for confirmation
+		if m.confirming {
+			switch msg.String() {
+			case "ctrl+c", "ctrl+d", "ctrl+z", "ctrl+q":
+				return m, tea.Quit
+			case "y", "Y":
+				command := m.pendingCommand
+				batch := m.pendingBatch
+				attached := m.pendingAttached
+				m.confirming = false
+				m.pendingCommand = ""
+				m.pendingReason = ""
+				m.pendingBatch = nil
+				m.pendingAttached = false
+				if batch != nil {
+					return m.executeBatch(batch)
+				}
+				if attached {
+					return m, executeInteractive(command)
+				}
+				return m.execute(command)
+			case "n", "N", "esc":
+				m.confirming = false
+				m.pendingCommand = ""
+				m.pendingReason = ""
+				m.pendingBatch = nil
+				m.pendingAttached = false
+			}
+			return m, nil
+		}
+
+		// The pinned commands view has its own key bindings
+		if m.pinnedView {
+			return m.updatePinnedView(msg)
+		}
+
 		// Handle special keys first
 		switch msg.String() {
 		case "ctrl+c", "ctrl+d", "ctrl+z", "ctrl+q":
 			return m, tea.Quit
 
+		case "ctrl+p":
+			// Pin the selected suggestion so it survives new queries
+			if !m.loading && len(m.suggestions) > 0 && !m.queryMode && !m.directCommandMode {
+				m.pinCommand(m.suggestions[m.selected].EditedCommand)
+				return m, nil
+			}
+
+		case "ctrl+t":
+			// Toggle the multi-line query input, carrying over what was typed
+			if !m.loading && m.queryMode {
+				m.multiline = !m.multiline
+				if m.multiline {
+					m.multilineInput.SetValue(m.input.Value())
+					m.input.Blur()
+					return m, m.multilineInput.Focus()
+				}
+				m.input.SetValue(strings.ReplaceAll(m.multilineInput.Value(), "\n", " "))
+				m.multilineInput.Blur()
+				m.input.Focus()
+				return m, nil
+			}
+
+		case "ctrl+s", "alt+enter":
+			// Submit a multi-line query; Enter inserts newlines there
+			if !m.loading && m.queryMode && m.multiline {
+				return m.submitQuery(m.multilineInput.Value())
+			}
+
+		case "ctrl+x":
+			// Run the command attached to the terminal so full-screen programs work
+			if !m.loading && !m.showResult {
+				if len(m.suggestions) > 0 && !m.queryMode && !m.directCommandMode {
+					return m.runCommand(m.suggestions[m.selected].EditedCommand, true)
+				} else if m.directCommandMode && m.input.Value() != "" {
+					command := m.input.Value()
+					m.input.SetValue("")
+					return m.runCommand(command, true)
+				}
+			}
+
+		case "ctrl+y":
+			// Copy the command instead of running it
+			if !m.loading && !m.showResult {
+				if len(m.suggestions) > 0 && !m.queryMode && !m.directCommandMode {
+					m.copyStatus = copyCommandStatus(m.suggestions[m.selected].EditedCommand)
+				} else if m.directCommandMode && m.input.Value() != "" {
+					m.copyStatus = copyCommandStatus(m.input.Value())
+				}
+				return m, nil
+			}
+
+		case "ctrl+o":
+			// Open the pinned commands view
+			if !m.loading && !m.showResult {
+				m.pinnedView = true
+				if m.pinnedSelected >= len(m.pinned) {
+					m.pinnedSelected = 0
+				}
+				return m, nil
+			}
+
 		case "tab":
 			// Toggle between modes: query -> direct command -> suggestions (if available)
 			if m.loading {
@@ -140,9 +356,14 @@ func (m VirtualTerminalModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 				return m, nil
 			}
+			if !m.loading && !m.showResult && m.queryMode && !m.multiline && len(m.suggestions) == 0 {
+				// Recall previous queries
+				m.recallQuery(msg.String() == "up")
+				return m, nil
+			}
 
 		case "enter":
-			if !m.loading {
+			if !m.loading && !(m.queryMode && m.multiline && !m.showResult) {
 				if m.showResult {
 					// Start a new query session instead of just hiding the result
 					m.showResult = false
@@ -158,29 +379,17 @@ func (m VirtualTerminalModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				} else if len(m.suggestions) > 0 && !m.queryMode && !m.directCommandMode {
 					// Execute the selected command
 					command := m.suggestions[m.selected].EditedCommand
-					return m, tea.Sequence(
-						executeCommand(command),
-						func() tea.Msg { return executeResultMsg{} },
-					)
+					return m.runCommand(command, false)
 				} else if m.directCommandMode {
 					// Execute direct command
 					command := m.input.Value()
 					if command != "" {
 						m.input.SetValue("")
-						return m, tea.Sequence(
-							executeCommand(command),
-							func() tea.Msg { return executeResultMsg{} },
-						)
+						return m.runCommand(command, false)
 					}
 				} else if m.queryMode {
 					// Submit the query to get suggestions
-					m.query = m.input.Value()
-					if m.query != "" {
-						m.loading = true
-						m.input.SetValue("")
-						m.queryMode = false
-						return m, getCommandSuggestions(m.query, m.config, m.adapter)
-					}
+					return m.submitQuery(m.input.Value())
 				}
 			}
 
@@ -271,33 +480,62 @@ func (m VirtualTerminalModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 		// Pass inputs to textinput when in appropriate modes
+		if m.queryMode && m.multiline && !m.directCommandMode {
+			m.multilineInput, cmd = m.multilineInput.Update(msg)
+			return m, cmd
+		}
 		if m.queryMode || m.directCommandMode {
 			m.input, cmd = m.input.Update(msg)
 			return m, cmd
 		}
 
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		return m, nil
+
+	case suggestionPartMsg:
+		// Keep draining answers to queries that were replaced
+		if msg.requestID != m.requestID {
+			return m, waitForSuggestionEvent(msg.events)
+		}
+
+		// Show suggestions as soon as the first one is complete
+		if m.loading {
+			m.loading = false
+			m.selected = 0
+			m.queryMode = false
+		}
+		m.streaming = true
+		m.suggestions = append(m.suggestions, newEditableSuggestion(msg.suggestion.Suggestion))
+		return m, waitForSuggestionEvent(msg.events)
+
 	case suggestionsMsg:
+		if msg.requestID != m.requestID {
+			return m, nil
+		}
+
 		// Set loading to false when suggestions are received
 		m.loading = false
+		m.streaming = false
 		if msg.err != nil {
 			m.err = msg.err
 			m.queryMode = true // Go back to query mode on error
 			return m, nil
 		}
 
-		// Initialize suggestions with both original and edited commands
-		m.suggestions = make([]CommandSuggestion, len(msg.suggestions))
-		for i, sugg := range msg.suggestions {
-			m.suggestions[i] = CommandSuggestion{
-				Command:        sugg.Command,
-				EditedCommand:  sugg.Command,
-				Description:    sugg.Description,
-				CursorPosition: len(sugg.Command), // Start cursor at end
-			}
+		// Keep streamed suggestions (and any edits) and add the rest of the final answer
+		if len(m.suggestions) == 0 {
+			m.selected = 0
+			m.queryMode = false
+		}
+		for i := len(m.suggestions); i < len(msg.suggestions); i++ {
+			suggestion := newEditableSuggestion(msg.suggestions[i].Suggestion)
+			suggestion.Notice = msg.suggestions[i].Notice
+			m.suggestions = append(m.suggestions, suggestion)
+		}
+		if m.config.ShowUsage && msg.usage.Total() > 0 {
+			m.usage = msg.usage.Summary()
 		}
-
-		m.selected = 0
-		m.queryMode = false
 		return m, nil
 
 	case cursorBlinkMsg:
@@ -318,14 +556,217 @@ func (m VirtualTerminalModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		return m, nil
 
+	case spinner.TickMsg:
+		if !m.running {
+			return m, nil
+		}
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+
+	case interactiveDoneMsg:
+		m.commandResult = fmt.Sprintf("\n%s exited with code %d\n", msg.command, msg.exitCode)
+		m.lastExitCode = msg.exitCode
+		return m.Update(executeResultMsg{})
+
 	case commandOutputMsg:
-		m.commandResult = string(msg)
+		m.running = false
+		m.commandResult = msg.output
+		m.lastExitCode = msg.exitCode
+		return m, nil
+
+	case explanationMsg:
+		if msg.err != nil {
+			m.explanations[msg.command] = "Could not fetch explanation: " + msg.err.Error()
+		} else {
+			m.explanations[msg.command] = msg.explanation
+		}
 		return m, nil
 	}
 
 	return m, nil
 }
 
+// submitQuery sends a query for command suggestions, ignoring empty queries
+func (m VirtualTerminalModel) submitQuery(query string) (tea.Model, tea.Cmd) {
+	if query == "" {
+		return m, nil
+	}
+
+	m.query = query
+	m.queryHistory = appendQueryHistory(m.queryHistory, query)
+	m.historyIndex = len(m.queryHistory)
+	m.historyDraft = ""
+	m.loading = true
+	m.input.SetValue("")
+	m.multilineInput.Reset()
+	m.queryMode = false
+	m.suggestions = nil
+	m.requestID++
+	return m, streamCommandSuggestions(m.query, m.config, m.adapter, m.requestID)
+}
+
+// pinCommand adds a command to the pinned list unless it's already there
+func (m *VirtualTerminalModel) pinCommand(command string) {
+	command = strings.TrimSpace(command)
+	if command == "" {
+		return
+	}
+	for _, pinned := range m.pinned {
+		if pinned == command {
+			return
+		}
+	}
+	m.pinned = append(m.pinned, command)
+}
+
+// updatePinnedView handles key presses while the pinned commands view is shown
+func (m VirtualTerminalModel) updatePinnedView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "ctrl+d", "ctrl+z", "ctrl+q":
+		return m, tea.Quit
+
+	case "esc", "ctrl+o":
+		m.pinnedView = false
+
+	case "up":
+		if len(m.pinned) > 0 {
+			m.pinnedSelected = (m.pinnedSelected - 1 + len(m.pinned)) % len(m.pinned)
+		}
+
+	case "down":
+		if len(m.pinned) > 0 {
+			m.pinnedSelected = (m.pinnedSelected + 1) % len(m.pinned)
+		}
+
+	case "enter":
+		if len(m.pinned) > 0 {
+			m.pinnedView = false
+			return m.runCommand(m.pinned[m.pinnedSelected], false)
+		}
+
+	case "a":
+		// Run every pinned command in order
+		if len(m.pinned) > 0 {
+			m.pinnedView = false
+			return m.runCommandBatch(m.pinned)
+		}
+
+	case "d", "delete", "backspace":
+		if len(m.pinned) > 0 {
+			m.pinned = append(m.pinned[:m.pinnedSelected:m.pinnedSelected], m.pinned[m.pinnedSelected+1:]...)
+			if m.pinnedSelected >= len(m.pinned) && m.pinnedSelected > 0 {
+				m.pinnedSelected--
+			}
+		}
+	}
+
+	return m, nil
+}
+
+// runCommandBatch executes several commands in order, asking for confirmation if any looks dangerous
+func (m VirtualTerminalModel) runCommandBatch(commands []string) (tea.Model, tea.Cmd) {
+	batch := make([]string, len(commands))
+	copy(batch, commands)
+
+	for _, command := range batch {
+		if dangerous, reason := utils.CheckDangerousCommand(command, m.config); dangerous {
+			m.confirming = true
+			m.pendingCommand = strings.Join(batch, "\n  ")
+			m.pendingReason = reason
+			m.pendingBatch = batch
+			return m, nil
+		}
+	}
+
+	return m.executeBatch(batch)
+}
+
+// runCommand executes a command, asking for confirmation first when it looks dangerous;
+// attached runs it with the TUI suspended and the terminal handed to the command
+func (m VirtualTerminalModel) runCommand(command string, attached bool) (tea.Model, tea.Cmd) {
+	if dangerous, reason := utils.CheckDangerousCommand(command, m.config); dangerous {
+		m.confirming = true
+		m.pendingCommand = command
+		m.pendingReason = reason
+		m.pendingAttached = attached
+
+		// Fetch an explanation unless it's already cached
+		if m.config.ExplainRisk {
+			if _, ok := m.explanations[command]; !ok {
+				return m, explainCommand(command, m.config, m.adapter)
+			}
+		}
+		return m, nil
+	}
+
+	if attached {
+		return m, executeInteractive(command)
+	}
+	return m.execute(command)
+}
+
+// execute runs a command, handing the terminal to it when it's interactive
+func (m VirtualTerminalModel) execute(command string) (tea.Model, tea.Cmd) {
+	if utils.IsInteractiveCommand(command, m.config.InteractiveCommandList()) {
+		return m, executeInteractive(command)
+	}
+	return m.startExecution(command, executeCommand(command))
+}
+
+// executeBatch runs several commands, handing the terminal to them when any is interactive
+func (m VirtualTerminalModel) executeBatch(batch []string) (tea.Model, tea.Cmd) {
+	for _, command := range batch {
+		if utils.IsInteractiveCommand(command, m.config.InteractiveCommandList()) {
+			return m, executeInteractive(strings.Join(batch, " && "))
+		}
+	}
+	return m.startExecution(strings.Join(batch, " && "), executeCommandBatch(batch))
+}
+
+// spinners maps spinner config names to bubbles spinners
+var spinners = map[string]spinner.Spinner{
+	"dot":     spinner.Dot,
+	"line":    spinner.Line,
+	"minidot": spinner.MiniDot,
+	"jump":    spinner.Jump,
+	"pulse":   spinner.Pulse,
+	"points":  spinner.Points,
+	"globe":   spinner.Globe,
+	"moon":    spinner.Moon,
+	"meter":   spinner.Meter,
+}
+
+// newExecutionSpinner creates the spinner for the configured style; "none" disables it
+// and unknown or empty names use "dot"
+func newExecutionSpinner(style string) (spinner.Model, bool) {
+	sp := spinner.New()
+	sp.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF9900"))
+	if style == "none" {
+		return sp, false
+	}
+	if s, ok := spinners[style]; ok {
+		sp.Spinner = s
+	} else {
+		sp.Spinner = spinner.Dot
+	}
+	return sp, true
+}
+
+// startExecution runs a command in the background, showing a spinner until it finishes
+func (m VirtualTerminalModel) startExecution(label string, run tea.Cmd) (tea.Model, tea.Cmd) {
+	m.running = true
+	m.runningCommand = label
+	m.runStarted = time.Now()
+	execute := tea.Sequence(
+		run,
+		func() tea.Msg { return executeResultMsg{} },
+	)
+	if m.spinnerEnabled {
+		return m, tea.Batch(m.spinner.Tick, execute)
+	}
+	return m, execute
+}
+
 // Cursor blinking functionality
 type cursorBlinkMsg struct{}
 
@@ -338,45 +779,93 @@ func blinkCursor() tea.Cmd {
 // Execute command
 func executeCommand(command string) tea.Cmd {
 	return func() tea.Msg {
-		// Log command execution
-		utils.LogCommandExecution(command)
+		output, exitCode := runCapturedCommand(command)
+		return commandOutputMsg{output: output, exitCode: exitCode}
+	}
+}
 
-		// Split the command into executable and arguments
-		parts := strings.Fields(command)
-		if len(parts) == 0 {
-			return commandOutputMsg("Error: Empty command")
+// executeInteractive suspends the TUI and runs a command attached to the terminal,
+// since programs like vim or ssh hang when their output is captured
+func executeInteractive(command string) tea.Cmd {
+	utils.LogCommandExecution(command)
+	return tea.ExecProcess(shellCommand(command), func(err error) tea.Msg {
+		return interactiveDoneMsg{command: command, exitCode: commandExitCode(err)}
+	})
+}
+
+// executeCommandBatch runs several commands in order and combines their output
+func executeCommandBatch(commands []string) tea.Cmd {
+	return func() tea.Msg {
+		var output strings.Builder
+		exitCode := 0
+		for _, command := range commands {
+			output.WriteString(color.CyanString("\n$ %s", command))
+			result, code := runCapturedCommand(command)
+			output.WriteString(result)
+			exitCode = code
 		}
+		return commandOutputMsg{output: output.String(), exitCode: exitCode}
+	}
+}
 
-		// Create a command with captured output
-		cmd := exec.Command(parts[0], parts[1:]...)
+// runCapturedCommand runs a command and returns its combined, formatted output and exit code
+func runCapturedCommand(command string) (string, int) {
+	// Log command execution
+	utils.LogCommandExecution(command)
 
-		// Capture both stdout and stderr
-		var stdout, stderr bytes.Buffer
-		cmd.Stdout = &stdout
-		cmd.Stderr = &stderr
+	if strings.TrimSpace(command) == "" {
+		return "Error: Empty command", common.ExitGeneric
+	}
 
-		err := cmd.Run()
+	// Run through the shell so quoting, pipes, redirects and globs work
+	cmd := shellCommand(command)
 
-		// Build the output
-		var output strings.Builder
-		output.WriteString("\n")
+	// Capture both stdout and stderr
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
 
-		if stdout.Len() > 0 {
-			output.WriteString(stdout.String())
-		}
+	err := cmd.Run()
 
-		if stderr.Len() > 0 {
-			output.WriteString("\nError output:\n")
-			output.WriteString(stderr.String())
-		}
+	// Build the output
+	var output strings.Builder
+	output.WriteString("\n")
 
-		if err != nil && stderr.Len() == 0 {
-			output.WriteString(fmt.Sprintf("\nCommand error: %v", err))
-		}
+	if stdout.Len() > 0 {
+		output.WriteString(stdout.String())
+	}
+
+	if stderr.Len() > 0 {
+		output.WriteString("\nError output:\n")
+		output.WriteString(stderr.String())
+	}
 
-		output.WriteString("\n")
-		return commandOutputMsg(output.String())
+	if err != nil && stderr.Len() == 0 {
+		output.WriteString(fmt.Sprintf("\nCommand error: %v", err))
 	}
+
+	output.WriteString("\n")
+	return output.String(), commandExitCode(err)
+}
+
+// copyCommandStatus copies a command to the clipboard and returns the line to show
+func copyCommandStatus(command string) string {
+	if err := utils.CopyToClipboard(command); err != nil {
+		return color.RedString("Copy failed: %v", err)
+	}
+	return color.GreenString("Copied!")
+}
+
+// commandExitCode returns the exit status of a finished command, 127 when it couldn't start
+func commandExitCode(err error) int {
+	if err == nil {
+		return common.ExitSuccess
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return 127
 }
 
 // View function with direct command editing
@@ -388,7 +877,67 @@ func (m VirtualTerminalModel) View() string {
 	s.WriteString(title + "\n\n")
 
 	if m.err != nil {
-		s.WriteString(color.RedString("Error: %v\n\n", m.err))
+		var filtered *ContentFilteredError
+		if errors.As(m.err, &filtered) {
+			s.WriteString(RenderError(m.err) + "\n")
+		} else {
+			s.WriteString(color.RedString("Error: %v\n\n", m.err))
+		}
+	}
+
+	// Show confirmation dialog for dangerous commands
+	if m.confirming {
+		warnStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#FF0000")).Bold(true)
+		s.WriteString(warnStyle.Render("WARNING: this command "+m.pendingReason) + "\n\n")
+		s.WriteString("  " + m.pendingCommand + "\n\n")
+
+		if m.config.ExplainRisk && m.pendingBatch == nil {
+			descStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#AAAAAA")).Italic(true).Width(80)
+			if explanation, ok := m.explanations[m.pendingCommand]; ok {
+				s.WriteString(descStyle.Render(explanation) + "\n\n")
+			} else {
+				s.WriteString(descStyle.Render("Fetching explanation...") + "\n\n")
+			}
+		}
+
+		keyStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#FF9900")).Bold(true)
+		s.WriteString(color.YellowString("Execute anyway? %s to run, %s to cancel\n",
+			keyStyle.Render("[y]"), keyStyle.Render("[n/Esc]")))
+
+		return s.String()
+	}
+
+	// Show pinned commands
+	if m.pinnedView {
+		s.WriteString(color.CyanString("[PINNED COMMANDS]") + "\n\n")
+		if len(m.pinned) == 0 {
+			s.WriteString("  No pinned commands yet.\n\n")
+		}
+		for i, command := range m.pinned {
+			if i == m.pinnedSelected {
+				commandStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#FFFF00")).Bold(true)
+				s.WriteString("> " + commandStyle.Render(command) + "\n")
+			} else {
+				s.WriteString("  " + command + "\n")
+			}
+		}
+
+		keyStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#FF9900")).Bold(true)
+		s.WriteString("\n" + color.YellowString("Use %s to select, %s to run, %s to run all, %s to unpin, %s to go back\n",
+			keyStyle.Render("[↑/↓]"), keyStyle.Render("[Enter]"), keyStyle.Render("[a]"),
+			keyStyle.Render("[d]"), keyStyle.Render("[Esc]")))
+
+		return s.String()
+	}
+
+	// Show progress while a command runs
+	if m.running {
+		elapsed := time.Since(m.runStarted).Truncate(time.Second)
+		if m.spinnerEnabled {
+			s.WriteString(m.spinner.View() + " ")
+		}
+		s.WriteString(fmt.Sprintf("Running %s (%s)\n", m.runningCommand, elapsed))
+		return s.String()
 	}
 
 	// Show command result if available
@@ -420,6 +969,8 @@ func (m VirtualTerminalModel) View() string {
 		s.WriteString(color.BlueString("[QUERY MODE] "))
 		if len(m.suggestions) > 0 {
 			s.WriteString(fmt.Sprintf("> %s\n\n", m.query))
+		} else if m.multiline {
+			s.WriteString("(multi-line)\n" + m.multilineInput.View() + "\n\n")
 		} else {
 			s.WriteString(fmt.Sprintf("> %s\n\n", m.input.View()))
 		}
@@ -430,35 +981,25 @@ func (m VirtualTerminalModel) View() string {
 
 	// Command suggestions with direct editing
 	if len(m.suggestions) > 0 && !m.directCommandMode {
-		for i, suggestion := range m.suggestions {
-			// Highlight selected suggestion
-			prefix := "  "
-			if i == m.selected {
-				prefix = "> "
-			}
-
-			// Display command with cursor
-			commandDisplay := suggestion.EditedCommand
-			if i == m.selected {
-				// Insert cursor at the right position
-				if m.cursorVisible {
-					pos := suggestion.CursorPosition
-					if pos >= 0 && pos <= len(commandDisplay) {
-						commandDisplay = commandDisplay[:pos] + "|" + commandDisplay[pos:]
-					}
-				}
-
-				// Highlight selected command
-				commandStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#FFFF00")).Bold(true)
-				s.WriteString(prefix + commandStyle.Render(commandDisplay) + "\n")
-			} else {
-				s.WriteString(prefix + commandDisplay + "\n")
+		if m.suggestions[0].Notice != "" {
+			s.WriteString(color.YellowString(m.suggestions[0].Notice) + "\n\n")
+		}
+		if columns := m.suggestionColumns(); columns > 1 {
+			s.WriteString(m.renderSuggestionColumns(columns))
+		} else {
+			for i := range m.suggestions {
+				s.WriteString(m.renderSuggestion(i, 0))
 			}
-
-			// Display description with a different color
-			descStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#AAAAAA")).Italic(true)
-			s.WriteString("    " + descStyle.Render(suggestion.Description) + "\n\n")
 		}
+		if m.streaming {
+			s.WriteString("Loading more suggestions...\n")
+		}
+		if m.usage != "" {
+			s.WriteString(color.New(color.Faint).Sprint(m.usage) + "\n")
+		}
+	}
+	if m.copyStatus != "" {
+		s.WriteString(m.copyStatus + "\n")
 	}
 
 	// Instructions based on current state with updated key styling
@@ -468,259 +1009,493 @@ func (m VirtualTerminalModel) View() string {
 		enterKey := keyStyle.Render("[Enter]")
 		tabKey := keyStyle.Render("[Tab]")
 		ctrlQKey := keyStyle.Render("[Ctrl+q]")
+		ctrlXKey := keyStyle.Render("[Ctrl+x]")
+		ctrlYKey := keyStyle.Render("[Ctrl+y]")
 		s.WriteString("\n" + color.YellowString("Type a command and press %s to execute, %s to switch modes, %s to quit\n",
 			enterKey, tabKey, ctrlQKey))
+		s.WriteString(color.YellowString("%s to run it in the full terminal, %s to copy it\n", ctrlXKey, ctrlYKey))
 	} else if !m.queryMode {
 		upDownKey := keyStyle.Render("[↑/↓]")
 		enterKey := keyStyle.Render("[Enter]")
 		tabKey := keyStyle.Render("[Tab]")
 		escKey := keyStyle.Render("[Esc]")
 		ctrlQKey := keyStyle.Render("[Ctrl+q]")
+		ctrlPKey := keyStyle.Render("[Ctrl+p]")
+		ctrlOKey := keyStyle.Render("[Ctrl+o]")
+		ctrlXKey := keyStyle.Render("[Ctrl+x]")
+		ctrlYKey := keyStyle.Render("[Ctrl+y]")
 		s.WriteString("\n" + color.YellowString("Edit directly, use %s to switch commands, %s to execute, %s to switch modes, %s to cancel, %s to quit\n",
 			upDownKey, enterKey, tabKey, escKey, ctrlQKey))
+		s.WriteString(color.YellowString("%s to pin the command, %s to show %d pinned, %s to run it in the full terminal, %s to copy it\n",
+			ctrlPKey, ctrlOKey, len(m.pinned), ctrlXKey, ctrlYKey))
 	} else {
 		tabKey := keyStyle.Render("[Tab]")
 		ctrlQKey := keyStyle.Render("[Ctrl+q]")
+		ctrlTKey := keyStyle.Render("[Ctrl+t]")
 		s.WriteString("\n" + color.YellowString("Type a query for command suggestions, %s to switch to direct command mode, %s to quit\n",
 			tabKey, ctrlQKey))
+		if m.multiline {
+			submitKey := keyStyle.Render("[Ctrl+s/Alt+Enter]")
+			s.WriteString(color.YellowString("%s to submit, Enter for a new line, %s for single-line input\n", submitKey, ctrlTKey))
+		} else {
+			upDownKey := keyStyle.Render("[↑/↓]")
+			s.WriteString(color.YellowString("%s for previous queries, %s for multi-line input\n", upDownKey, ctrlTKey))
+		}
 	}
 
 	return s.String()
 }
 
+// suggestionColumns returns how many suggestion columns fit the terminal
+func (m VirtualTerminalModel) suggestionColumns() int {
+	columnWidth := m.config.MaxSuggestionsWidth
+	if columnWidth <= 0 || m.width < 2*columnWidth {
+		return 1
+	}
+	return m.width / columnWidth
+}
+
+// renderSuggestion renders one suggestion; width limits the block when it's above zero
+func (m VirtualTerminalModel) renderSuggestion(i int, width int) string {
+	suggestion := m.suggestions[i]
+
+	// Highlight selected suggestion
+	prefix := "  "
+	if i == m.selected {
+		prefix = "> "
+	}
+
+	commandStyle := lipgloss.NewStyle()
+	descStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#AAAAAA")).Italic(true)
+	if width > 0 {
+		commandStyle = commandStyle.Width(width - len(prefix))
+		descStyle = descStyle.Width(width - 4)
+	}
+
+	// Display command with cursor
+	commandDisplay := suggestion.EditedCommand
+	if i == m.selected {
+		// Insert cursor at the right position
+		if m.cursorVisible {
+			pos := suggestion.CursorPosition
+			if pos >= 0 && pos <= len(commandDisplay) {
+				commandDisplay = commandDisplay[:pos] + "|" + commandDisplay[pos:]
+			}
+		}
+
+		// Highlight selected command
+		commandStyle = commandStyle.Foreground(lipgloss.Color("#FFFF00")).Bold(true)
+	}
+
+	// Display description with a different color
+	return prefix + commandStyle.Render(commandDisplay) + "\n" +
+		"    " + descStyle.Render(suggestion.Description) + "\n\n"
+}
+
+// renderSuggestionColumns lays suggestions out in rows of side-by-side columns
+func (m VirtualTerminalModel) renderSuggestionColumns(columns int) string {
+	columnWidth := m.width / columns
+	blockStyle := lipgloss.NewStyle().Width(columnWidth)
+
+	var rows []string
+	for start := 0; start < len(m.suggestions); start += columns {
+		var blocks []string
+		for i := start; i < start+columns && i < len(m.suggestions); i++ {
+			blocks = append(blocks, blockStyle.Render(m.renderSuggestion(i, columnWidth-1)))
+		}
+		rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top, blocks...))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, rows...) + "\n"
+}
+
 // Message types for the update function
 type suggestionsMsg struct {
 	suggestions []CommandSuggestion
+	usage       utils.TokenUsage
 	err         error
+	requestID   int
+}
+
+// suggestionPartMsg carries one suggestion parsed while the answer is still streaming
+type suggestionPartMsg struct {
+	suggestion CommandSuggestion
+	requestID  int
+	events     <-chan tea.Msg // delivers the next part or the final suggestionsMsg
 }
 
 type executeResultMsg struct{}
 
-type commandOutputMsg string
+type commandOutputMsg struct {
+	output   string
+	exitCode int
+}
 
-// Function to get command suggestions from the AI
-func getCommandSuggestions(query string, conf *config.Config, adapter relay.AIAdapter) tea.Cmd {
-	return func() tea.Msg {
-		// Build the request
-		request := utils.BuildPrompt(query, conf, "terminal")
+// interactiveDoneMsg reports that a command run with the TUI suspended has exited
+type interactiveDoneMsg struct {
+	command  string
+	exitCode int
+}
 
-		// Send the request with timeout
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-		defer cancel()
+type explanationMsg struct {
+	command     string
+	explanation string
+	err         error
+}
 
-		// Convert AIAdapter to Adapter to access ChatCompletion
+// explainCommand asks the AI for a short explanation of what a command does
+func explainCommand(command string, conf *config.Config, adapter relay.AIAdapter) tea.Cmd {
+	return func() tea.Msg {
 		adapterImpl, ok := adapter.(relay.Adapter)
 		if !ok {
-			return suggestionsMsg{nil, fmt.Errorf("adapter does not implement required interface")}
+			return explanationMsg{command: command, err: fmt.Errorf("adapter does not implement required interface")}
 		}
 
-		// Create a response channel and error channel
-		responseChan := make(chan *dto.OpenAITextResponse, 1)
-		errChan := make(chan error, 1)
+		query := "Explain in one short paragraph what the following command will do, " +
+			"including which files or system state it changes and whether it can be undone:\n" + command
+		request := utils.BuildPrompt(query, conf, "chat")
+		request.MaxTokens = 300
+
+		ctx, cancel := utils.RequestContext(conf)
+		defer cancel()
 
-		// Execute request in goroutine to allow for timeout handling
+		response, err := adapterImpl.ChatCompletion(ctx, request)
+		if err != nil {
+			return explanationMsg{command: command, err: err}
+		}
+		content, err := responseContent(response)
+		if err != nil {
+			return explanationMsg{command: command, err: err}
+		}
+
+		return explanationMsg{command: command, explanation: strings.TrimSpace(content)}
+	}
+}
+
+// newEditableSuggestion prepares a suggestion for editing with the cursor at the end
+func newEditableSuggestion(sugg utils.Suggestion) CommandSuggestion {
+	return CommandSuggestion{
+		Suggestion:     sugg,
+		EditedCommand:  sugg.Command,
+		CursorPosition: len(sugg.Command),
+	}
+}
+
+// streamCommandSuggestions streams command suggestions from the AI, sending each
+// one to the TUI as it completes and the full list once the answer ends
+func streamCommandSuggestions(query string, conf *config.Config, adapter relay.AIAdapter, requestID int) tea.Cmd {
+	return func() tea.Msg {
+		events := make(chan tea.Msg)
 		go func() {
-			response, err := adapterImpl.ChatCompletion(ctx, request)
-			if err != nil {
-				errChan <- err
-				return
-			}
-			responseChan <- response
+			defer close(events)
+			suggestions, usage, err := StreamCommandSuggestions(query, conf, adapter, func(sugg CommandSuggestion) {
+				events <- suggestionPartMsg{suggestion: sugg, requestID: requestID, events: events}
+			})
+			events <- suggestionsMsg{suggestions: suggestions, usage: usage, err: err, requestID: requestID}
 		}()
+		return <-events
+	}
+}
 
-		// Wait for response or timeout
-		select {
-		case response := <-responseChan:
-			if len(response.Choices) == 0 {
-				return suggestionsMsg{nil, fmt.Errorf("no suggestions received")}
-			}
+// waitForSuggestionEvent waits for the next streamed suggestion event
+func waitForSuggestionEvent(events <-chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-events
+		if !ok {
+			return nil
+		}
+		return msg
+	}
+}
 
-			// Get the response content
-			content := response.Choices[0].Message.StringContent()
+// StreamCommandSuggestions asks the AI for command suggestions over a streamed
+// answer, calling onSuggestion for each suggestion as soon as it is complete. It also
+// returns the tokens used, estimated when the provider doesn't report them.
+func StreamCommandSuggestions(query string, conf *config.Config, adapter relay.AIAdapter, onSuggestion func(CommandSuggestion)) ([]CommandSuggestion, utils.TokenUsage, error) {
+	request := utils.BuildPrompt(query, conf, "terminal")
 
-			// Parse the JSON response
-			var rawSuggestions []map[string]map[string]string
-			if err := json.Unmarshal([]byte(content), &rawSuggestions); err != nil {
-				// Try to handle non-JSON formatted responses
-				// Log original content for debugging
-				utils.LogError("Failed to parse suggestions JSON", fmt.Errorf("content: %s, error: %v", content, err))
+	ctx, cancel := utils.RequestContext(conf)
+	defer cancel()
 
-				// Try to extract commands using a fallback approach
-				suggestions := extractCommandsFromText(content)
-				if len(suggestions) > 0 {
-					return suggestionsMsg{suggestions, nil}
-				}
+	adapterImpl, ok := adapter.(relay.Adapter)
+	if !ok {
+		return nil, utils.TokenUsage{}, fmt.Errorf("adapter does not implement required interface")
+	}
 
-				return suggestionsMsg{nil, fmt.Errorf("failed to parse suggestions: %w", err)}
-			}
+	stream, err := adapterImpl.ChatCompletionStream(ctx, request)
+	if err != nil {
+		if suggestions, ok := cachedSuggestions(query, conf, err); ok {
+			return suggestions, utils.TokenUsage{}, nil
+		}
+		return nil, utils.TokenUsage{}, fmt.Errorf("API error: %w", err)
+	}
 
-			// Convert to CommandSuggestion objects
-			var suggestions []CommandSuggestion
-			for _, item := range rawSuggestions {
-				for _, cmdMap := range item {
-					for cmd, desc := range cmdMap {
-						suggestions = append(suggestions, CommandSuggestion{
-							Command:     cmd,
-							Description: desc,
-						})
-					}
-				}
+	var parser utils.SuggestionStream
+	var streamed []CommandSuggestion
+	var filtered *ContentFilteredError
+	var reported *dto.Usage
+	for response := range stream {
+		if response.Usage != nil {
+			reported = response.Usage
+		}
+		if len(response.Choices) == 0 {
+			continue
+		}
+		choice := response.Choices[0]
+		if refusal := choice.Delta.GetRefusal(); refusal != "" {
+			if filtered == nil {
+				filtered = &ContentFilteredError{}
 			}
+			filtered.Reason += refusal
+		}
+		if choice.FinishReason != nil && *choice.FinishReason == dto.FinishReasonContentFilter && filtered == nil {
+			filtered = &ContentFilteredError{}
+		}
+		if choice.Delta.Content == nil {
+			continue
+		}
+		for _, sugg := range parser.Feed(*choice.Delta.Content) {
+			suggestion := CommandSuggestion{Suggestion: sugg}
+			streamed = append(streamed, suggestion)
+			onSuggestion(suggestion)
+		}
+	}
 
-			// Log the suggestions for history
-			commandMap := make(map[string]string)
-			for _, sugg := range suggestions {
-				commandMap[sugg.Command] = sugg.Description
-			}
-			logger := utils.NewLogger()
-			if err := logger.LogCommand(query, commandMap); err != nil {
-				utils.LogError("Failed to log command history", err)
-			}
+	if filtered != nil {
+		return nil, utils.TokenUsage{}, filtered
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, utils.TokenUsage{}, fmt.Errorf("request timed out after %s (request_timeout): %w", conf.RequestTimeout(), context.DeadlineExceeded)
+	}
+	content := parser.Content()
+	if strings.TrimSpace(content) == "" && conf.RetryOnEmpty {
+		content = retryWithoutStream(ctx, adapterImpl, request)
+	}
+	if strings.TrimSpace(content) == "" {
+		return nil, utils.TokenUsage{}, ErrNoContent
+	}
 
-			// Log the successful suggestions
-			utils.LogInfo(fmt.Sprintf("Generated %d command suggestions for query: %s", len(suggestions), query))
+	// Parse the whole answer, which also covers formats the stream parser can't split
+	suggestions, err := parseCommandSuggestions(content)
+	if err != nil {
+		if len(streamed) == 0 {
+			return nil, utils.TokenUsage{}, err
+		}
+		suggestions = streamed
+	}
 
-			return suggestionsMsg{suggestions, nil}
+	cacheResponse(conf, "terminal", query, content)
+	logSuggestions(query, suggestions)
+	return suggestions, utils.UsageFor(request.Model, reported, request, content), nil
+}
+
+// RequestCommandSuggestions asks the AI for command suggestions and parses the answer
+func RequestCommandSuggestions(query string, conf *config.Config, adapter relay.AIAdapter) ([]CommandSuggestion, error) {
+	suggestions, _, err := requestCommandSuggestions(query, conf, adapter)
+	return suggestions, err
+}
 
-		case err := <-errChan:
-			return suggestionsMsg{nil, fmt.Errorf("API error: %w", err)}
+// requestCommandSuggestions is RequestCommandSuggestions that also returns the tokens used
+func requestCommandSuggestions(query string, conf *config.Config, adapter relay.AIAdapter) ([]CommandSuggestion, utils.TokenUsage, error) {
+	// Build the request
+	request := utils.BuildPrompt(query, conf, "terminal")
 
-		case <-time.After(35 * time.Second):
-			// Cancel the context if timeout occurs
-			cancel()
-			return suggestionsMsg{nil, fmt.Errorf("request timed out after 35 seconds")}
+	// Send the request, bounded by request_timeout
+	ctx, cancel := utils.RequestContext(conf)
+	defer cancel()
+
+	// Convert AIAdapter to Adapter to access ChatCompletion
+	adapterImpl, ok := adapter.(relay.Adapter)
+	if !ok {
+		return nil, utils.TokenUsage{}, fmt.Errorf("adapter does not implement required interface")
+	}
+
+	response, err := adapterImpl.ChatCompletion(ctx, request)
+	if err != nil {
+		if suggestions, ok := cachedSuggestions(query, conf, err); ok {
+			return suggestions, utils.TokenUsage{}, nil
+		}
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, utils.TokenUsage{}, fmt.Errorf("request timed out after %s (request_timeout): %w", conf.RequestTimeout(), err)
 		}
+		return nil, utils.TokenUsage{}, fmt.Errorf("API error: %w", err)
+	}
+
+	// Get the response content and parse it
+	content, err := responseContent(response)
+	if err != nil {
+		return nil, utils.TokenUsage{}, err
+	}
+	suggestions, err := parseCommandSuggestions(content)
+	if err != nil {
+		return nil, utils.TokenUsage{}, err
 	}
-}
 
-// Helper function to extract commands from non-JSON responses
-func extractCommandsFromText(content string) []CommandSuggestion {
-	var suggestions []CommandSuggestion
-	lines := strings.Split(content, "\n")
+	cacheResponse(conf, "terminal", query, content)
+	logSuggestions(query, suggestions)
+	return suggestions, utils.UsageFor(request.Model, &response.Usage, request, content), nil
+}
 
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
+// logSuggestions records generated suggestions in the command history
+func logSuggestions(query string, suggestions []CommandSuggestion) {
+	commandMap := make(map[string]string)
+	for _, sugg := range suggestions {
+		commandMap[sugg.Command] = sugg.Description
+	}
+	logger := utils.NewLogger()
+	if err := logger.LogCommand(query, commandMap); err != nil {
+		utils.LogError("Failed to log command history", err)
+	}
 
-		// Look for patterns like "command - description" or "command: description"
-		for _, separator := range []string{" - ", ": "} {
-			if parts := strings.SplitN(line, separator, 2); len(parts) == 2 {
-				cmd := strings.TrimSpace(parts[0])
-				desc := strings.TrimSpace(parts[1])
+	// Log the successful suggestions
+	utils.LogInfo(fmt.Sprintf("Generated %d command suggestions for query: %s", len(suggestions), query))
+}
 
-				// Skip if it doesn't look like a command
-				if len(cmd) > 0 && !strings.HasPrefix(cmd, "#") && !strings.HasPrefix(cmd, "//") {
-					suggestions = append(suggestions, CommandSuggestion{
-						Command:     cmd,
-						Description: desc,
-					})
-					break
-				}
-			}
-		}
+// parseCommandSuggestions converts the raw model answer into command suggestions
+func parseCommandSuggestions(content string) ([]CommandSuggestion, error) {
+	parsed, err := utils.ParseSuggestions(content)
+	if err != nil {
+		// Log original content for debugging
+		utils.LogError("Failed to parse suggestions", fmt.Errorf("content: %s, error: %v", content, err))
+		return nil, err
 	}
 
-	return suggestions
+	suggestions := make([]CommandSuggestion, len(parsed))
+	for i, sugg := range parsed {
+		suggestions[i] = CommandSuggestion{Suggestion: sugg}
+	}
+	return suggestions, nil
 }
 
 // StartVirtualTerminalMode starts the virtual terminal mode
 func StartVirtualTerminalMode(conf *config.Config) {
 	p := tea.NewProgram(NewVirtualTerminalModel(conf))
-	if _, err := p.Run(); err != nil {
+	final, err := p.Run()
+	if err != nil {
 		fmt.Printf("Error running virtual terminal: %v\n", err)
 		os.Exit(1)
 	}
+
+	// Exit with the status of the last executed command
+	if m, ok := final.(VirtualTerminalModel); ok && m.lastExitCode != 0 {
+		os.Exit(m.lastExitCode)
+	}
 }
 
-// StartCommandMode starts the command mode with a query
+// StartCommandMode starts the command mode with a query and prints the parsed suggestions
 func StartCommandMode(query string, conf *config.Config) {
+	if strings.TrimSpace(query) == "" {
+		fmt.Printf("Error: %v\n", ErrEmptyQuery)
+		os.Exit(common.ExitGeneric)
+	}
+
 	// Get the adapter
 	adapter, err := relay.NewAdapter(conf)
 	if err != nil {
 		fmt.Printf("Error initializing adapter: %v\n", err)
-		os.Exit(1)
+		os.Exit(common.ExitConfig)
 	}
 
-	// Create service
-	aiService := service.NewAIService(adapter)
-
-	// Create command mode
-	cmdMode := NewCommandMode(aiService, conf.ModelName)
+	utils.LogUserRequest(query, "command")
 
-	// Process the query
-	err = cmdMode.ProcessQuery(query, conf.SysPrompt, true)
+	suggestions, usage, err := requestCommandSuggestions(query, conf, adapter)
 	if err != nil {
 		fmt.Printf("Error processing query: %v\n", err)
-		os.Exit(1)
+		os.Exit(ExitCodeFor(err))
 	}
-}
+	defer printUsage(conf, usage)
 
-// NewCommandMode creates a new command mode
-func NewCommandMode(aiService *service.AIService, model string) *CommandMode {
-	return &CommandMode{
-		aiService: aiService,
-		model:     model,
+	if len(suggestions) == 0 {
+		fmt.Println("No command suggestions received.")
+		return
 	}
-}
-
-// CommandMode handles command processing
-type CommandMode struct {
-	aiService *service.AIService
-	model     string
-}
 
-// ProcessQuery processes a command query
-func (c *CommandMode) ProcessQuery(query string, systemPrompt string, stream bool) error {
-	messages := []dto.Message{
-		{
-			Role: "system",
-		},
-		{
-			Role: "user",
-		},
+	// An explicit --output formats the suggestions as markdown
+	if conf.Output != "" {
+		formatter, err := NewFormatter(conf.Output)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(common.ExitConfig)
+		}
+		// Drop stray blank lines around the output so piped output is predictable
+		fmt.Println(strings.Trim(formatter.Format(suggestionsMarkdown(suggestions)), "\r\n"))
+		return
 	}
 
-	messages[0].SetStringContent(systemPrompt)
-	messages[1].SetStringContent(query)
+	if suggestions[0].Notice != "" {
+		fmt.Println(color.YellowString(suggestions[0].Notice))
+	}
+	printCommandSuggestions(suggestions)
+}
 
-	ctx := context.Background()
+// PrintCommandSuggestionsJSON prints the suggestions for a query as a JSON array of
+// {"command", "description"} objects for scripts and editor plugins, indented when
+// pretty is set. Errors go to stderr so stdout only ever holds the JSON.
+func PrintCommandSuggestionsJSON(query string, conf *config.Config, pretty bool) {
+	if strings.TrimSpace(query) == "" {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", ErrEmptyQuery)
+		os.Exit(common.ExitGeneric)
+	}
 
-	if stream {
-		return c.handleStreamingResponse(ctx, messages)
+	adapter, err := relay.NewAdapter(conf)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing adapter: %v\n", err)
+		os.Exit(common.ExitConfig)
 	}
-	return c.handleNonStreamingResponse(ctx, messages)
-}
 
-// handleNonStreamingResponse handles non-streaming response
-func (c *CommandMode) handleNonStreamingResponse(ctx context.Context, messages []dto.Message) error {
-	response, err := c.aiService.SendChatRequest(ctx, messages, c.model)
+	utils.LogUserRequest(query, "command")
+
+	suggestions, usage, err := requestCommandSuggestions(query, conf, adapter)
 	if err != nil {
-		return err
+		fmt.Fprintf(os.Stderr, "Error processing query: %v\n", err)
+		os.Exit(ExitCodeFor(err))
+	}
+	defer printUsage(conf, usage)
+	if len(suggestions) == 0 {
+		fmt.Fprintln(os.Stderr, "No command suggestions received.")
+		os.Exit(common.ExitNoContent)
+	}
+	if suggestions[0].Notice != "" {
+		fmt.Fprintln(os.Stderr, suggestions[0].Notice)
 	}
 
-	if len(response.Choices) > 0 {
-		content := response.Choices[0].Message.StringContent()
-		fmt.Print(content)
+	list := make([]utils.Suggestion, len(suggestions))
+	for i, suggestion := range suggestions {
+		list[i] = suggestion.Suggestion
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	if pretty {
+		encoder.SetIndent("", "  ")
+	}
+	if err := encoder.Encode(list); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing JSON: %v\n", err)
+		os.Exit(common.ExitGeneric)
 	}
-	return nil
 }
 
-// handleStreamingResponse handles streaming response
-func (c *CommandMode) handleStreamingResponse(ctx context.Context, messages []dto.Message) error {
-	responseStream, err := c.aiService.SendStreamingChatRequest(ctx, messages, c.model)
-	if err != nil {
-		return err
+// suggestionsMarkdown formats suggestions as markdown with each command in its own code block
+func suggestionsMarkdown(suggestions []CommandSuggestion) string {
+	var s strings.Builder
+	for i, suggestion := range suggestions {
+		fmt.Fprintf(&s, "%d. %s\n\n```sh\n%s\n```\n\n", i+1, suggestion.Description, suggestion.Command)
 	}
+	return s.String()
+}
 
-	for response := range responseStream {
-		if len(response.Choices) > 0 && response.Choices[0].Delta.Content != nil {
-			fmt.Print(*response.Choices[0].Delta.Content)
-			// Flush stdout to ensure immediate display
-			os.Stdout.Sync()
+// printCommandSuggestions writes a numbered list of suggestions to stdout, separated
+// by blank lines and ending with a single newline
+func printCommandSuggestions(suggestions []CommandSuggestion) {
+	commandStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#FFFF00")).Bold(true)
+	descStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#AAAAAA")).Italic(true)
+
+	for i, suggestion := range suggestions {
+		if i > 0 {
+			fmt.Println()
+		}
+		// Whitespace the model put around a command or description is dropped,
+		// line breaks inside them are kept
+		fmt.Printf("%d. %s\n", i+1, commandStyle.Render(strings.TrimSpace(suggestion.Command)))
+		if description := strings.TrimSpace(suggestion.Description); description != "" {
+			fmt.Println("    " + descStyle.Render(description))
 		}
 	}
-	fmt.Println() // Add final newline
-	return nil
 }
@@ -0,0 +1,155 @@
+package terminal
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"ask_terminal/config"
+	"ask_terminal/dto"
+	"ask_terminal/relay"
+	"ask_terminal/utils"
+)
+
+func TestMain(m *testing.M) {
+	// Keep logs, history and the response cache out of the real data dir
+	dir, err := os.MkdirTemp("", "askta_test")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("XDG_DATA_HOME", dir)
+	utils.SetLoggingEnabled(false)
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// cannedAdapter answers every chat completion with the same content
+type cannedAdapter struct {
+	content string
+	err     error
+	request *dto.GeneralOpenAIRequest
+}
+
+func (a *cannedAdapter) Init(baseURL, apiKey string, proxyURL string, httpTimeout time.Duration) error {
+	return nil
+}
+
+func (a *cannedAdapter) ChatCompletion(ctx context.Context, request *dto.GeneralOpenAIRequest) (*dto.OpenAITextResponse, error) {
+	a.request = request
+	if a.err != nil {
+		return nil, a.err
+	}
+	choice := dto.OpenAITextResponseChoice{FinishReason: "stop"}
+	choice.Message.Role = "assistant"
+	choice.Message.SetStringContent(a.content)
+	return &dto.OpenAITextResponse{Choices: []dto.OpenAITextResponseChoice{choice}}, nil
+}
+
+func (a *cannedAdapter) ChatCompletionStream(ctx context.Context, request *dto.GeneralOpenAIRequest) (chan *dto.ChatCompletionsStreamResponse, error) {
+	return nil, errors.New("not streamed")
+}
+
+func (a *cannedAdapter) Embeddings(ctx context.Context, input []string, model string) ([][]float32, error) {
+	return nil, errors.New("no embeddings")
+}
+
+func (a *cannedAdapter) RawRequest(ctx context.Context, body []byte) (int, []byte, error) {
+	return 0, nil, errors.New("no raw requests")
+}
+
+func (a *cannedAdapter) Capabilities() relay.ProviderCapabilities {
+	return relay.ProviderCapabilities{JSONMode: true}
+}
+
+func (a *cannedAdapter) ProcessQuery(query string) (string, error) {
+	return a.content, a.err
+}
+
+func TestRequestCommandSuggestions(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []utils.Suggestion
+	}{
+		{
+			name:    "terminal prompt JSON",
+			content: `[{"1": {"du -sh * | sort -h": "sizes of entries, smallest first"}}, {"2": {"ncdu": "browse disk usage"}}]`,
+			want: []utils.Suggestion{
+				{Command: "du -sh * | sort -h", Description: "sizes of entries, smallest first"},
+				{Command: "ncdu", Description: "browse disk usage"},
+			},
+		},
+		{
+			name:    "fenced JSON after an introduction",
+			content: "Sure!\n\n```json\n{\"1\": {\"git log --oneline -5\": \"last five commits\"}}\n```",
+			want:    []utils.Suggestion{{Command: "git log --oneline -5", Description: "last five commits"}},
+		},
+		{
+			name:    "plain text lines",
+			content: "\n  `ls -lt` - newest files first\n  ls -lS - largest files first\n",
+			want: []utils.Suggestion{
+				{Command: "ls -lt", Description: "newest files first"},
+				{Command: "ls -lS", Description: "largest files first"},
+			},
+		},
+		{
+			name:    "prose and duplicates dropped",
+			content: `[{"1": {"Use the command below to check.": ""}}, {"2": {"df -h": "free space"}}, {"3": {"df  -h": "again"}}]`,
+			want:    []utils.Suggestion{{Command: "df -h", Description: "free space"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adapter := &cannedAdapter{content: tt.content}
+			conf := &config.Config{ModelName: "test-model", PrivateMode: true}
+
+			suggestions, _, err := requestCommandSuggestions("disk usage", conf, adapter)
+			if err != nil {
+				t.Fatalf("requestCommandSuggestions() error = %v", err)
+			}
+			got := make([]utils.Suggestion, len(suggestions))
+			for i, suggestion := range suggestions {
+				got[i] = suggestion.Suggestion
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("requestCommandSuggestions() = %#v, want %#v", got, tt.want)
+			}
+
+			// Suggestions are asked for deterministically, in JSON
+			request := adapter.request
+			if request.Temperature == nil || *request.Temperature != 0 {
+				t.Errorf("request temperature = %v, want 0", request.Temperature)
+			}
+			if request.ResponseFormat == nil || request.ResponseFormat.Type != "json_object" {
+				t.Errorf("request response_format = %#v, want json_object", request.ResponseFormat)
+			}
+		})
+	}
+}
+
+func TestRequestCommandSuggestionsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		adapter *cannedAdapter
+	}{
+		{name: "no commands in the answer", adapter: &cannedAdapter{content: "[]"}},
+		{name: "empty answer", adapter: &cannedAdapter{content: ""}},
+		{name: "provider error", adapter: &cannedAdapter{err: errors.New("connection refused")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := &config.Config{ModelName: "test-model", PrivateMode: true}
+			suggestions, _, err := requestCommandSuggestions("disk usage", conf, tt.adapter)
+			if err == nil {
+				t.Errorf("requestCommandSuggestions() = %#v, want an error", suggestions)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package terminal
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"ask_terminal/config"
+	"ask_terminal/dto"
+	"ask_terminal/relay"
+	"ask_terminal/utils"
+
+	"github.com/fatih/color"
+)
+
+// CompareResult holds the answer of a single model in compare mode
+type CompareResult struct {
+	Model   string
+	Content string
+	Latency time.Duration
+	Usage   dto.Usage
+	Err     error
+}
+
+// ParseModelList splits a comma separated model list, dropping empty entries
+func ParseModelList(models string) []string {
+	var result []string
+	for _, model := range strings.Split(models, ",") {
+		model = strings.TrimSpace(model)
+		if model != "" {
+			result = append(result, model)
+		}
+	}
+	return result
+}
+
+// runCompareQuery sends the query to a single model and measures the latency
+func runCompareQuery(query string, model string, conf *config.Config) CompareResult {
+	result := CompareResult{Model: model}
+
+	// Work on a copy so each model gets its own request settings
+	modelConf := *conf
+	modelConf.ModelName = model
+	modelConf.ChatModel = ""
+
+	adapter, err := relay.NewAdapter(&modelConf)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	request := utils.BuildPrompt(query, &modelConf, "chat")
+
+	ctx, cancel := utils.RequestContext(&modelConf)
+	defer cancel()
+
+	start := time.Now()
+	response, err := adapter.ChatCompletion(ctx, request)
+	result.Latency = time.Since(start)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	result.Usage = response.Usage
+
+	result.Content, result.Err = responseContent(response)
+
+	return result
+}
+
+// StartCompareMode sends the same query to several models and prints their answers sequentially
+func StartCompareMode(query string, models []string, conf *config.Config) {
+	if query == "" {
+		fmt.Println("Error: --compare requires a query")
+		os.Exit(1)
+	}
+	if len(models) == 0 {
+		fmt.Println("Error: --compare requires at least one model in --models")
+		os.Exit(1)
+	}
+
+	utils.LogInfo(fmt.Sprintf("Starting Compare Mode for models %s with query: %s", strings.Join(models, ","), query))
+
+	var results []CompareResult
+	for _, model := range models {
+		printStatus(conf, "Querying %s...", model)
+		results = append(results, runCompareQuery(query, model, conf))
+	}
+
+	fmt.Println()
+	for _, result := range results {
+		fmt.Println(color.CyanString("=== %s ===", result.Model))
+		if result.Err != nil {
+			fmt.Print(RenderError(result.Err))
+			utils.LogError("Compare mode request failed for model "+result.Model, result.Err)
+			fmt.Println()
+			continue
+		}
+
+		rendered, err := renderMarkdown(result.Content)
+		if err != nil {
+			rendered = result.Content
+		}
+		fmt.Println(rendered)
+		fmt.Println(color.YellowString("Latency: %s • Tokens: %d prompt / %d completion / %d total",
+			result.Latency.Round(time.Millisecond),
+			result.Usage.PromptTokens,
+			result.Usage.CompletionTokens,
+			result.Usage.TotalTokens))
+		fmt.Println()
+	}
+
+	// Summary table
+	fmt.Println(color.CyanString("=== Summary ==="))
+	fmt.Printf("%-30s %12s %10s\n", "Model", "Latency", "Tokens")
+	for _, result := range results {
+		if result.Err != nil {
+			fmt.Printf("%-30s %12s %10s\n", result.Model, "failed", "-")
+			continue
+		}
+		fmt.Printf("%-30s %12s %10d\n", result.Model, result.Latency.Round(time.Millisecond), result.Usage.TotalTokens)
+	}
+}
@@ -0,0 +1,46 @@
+package terminal
+
+import (
+	"ask_terminal/common"
+	"ask_terminal/relay"
+	"context"
+	"errors"
+	"net"
+	"os/exec"
+)
+
+// isNetworkError reports whether err is a network failure or timeout
+func isNetworkError(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// ExitCodeFor maps an error to the exit code contract in common
+func ExitCodeFor(err error) int {
+	if err == nil {
+		return common.ExitSuccess
+	}
+
+	var filtered *ContentFilteredError
+	if errors.Is(err, ErrNoContent) || errors.As(err, &filtered) {
+		return common.ExitNoContent
+	}
+
+	var apiErr *relay.APIError
+	if errors.As(err, &apiErr) && apiErr.IsAuth() {
+		return common.ExitAuth
+	}
+
+	var circuitErr *relay.CircuitOpenError
+	if isNetworkError(err) || errors.As(err, &circuitErr) {
+		return common.ExitNetwork
+	}
+
+	// Pass through the executed command's own exit status
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+
+	return common.ExitGeneric
+}
@@ -0,0 +1,164 @@
+package terminal
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"ask_terminal/utils"
+
+	"github.com/fatih/color"
+)
+
+// Formatter turns an AI answer into the text printed for the chosen --output
+type Formatter interface {
+	Format(content string) string
+}
+
+// PlainFormatter prints the answer unchanged
+type PlainFormatter struct{}
+
+func (PlainFormatter) Format(content string) string {
+	return strings.TrimRight(content, "\n") + "\n"
+}
+
+// GlamourFormatter renders markdown for the terminal
+type GlamourFormatter struct{}
+
+func (GlamourFormatter) Format(content string) string {
+	rendered, err := renderMarkdown(content)
+	if err != nil {
+		return PlainFormatter{}.Format(content)
+	}
+	return rendered
+}
+
+// JSONFormatter wraps the answer in a JSON object
+type JSONFormatter struct{}
+
+func (JSONFormatter) Format(content string) string {
+	data, err := json.Marshal(map[string]string{"content": content})
+	if err != nil {
+		return PlainFormatter{}.Format(content)
+	}
+	return string(data) + "\n"
+}
+
+// CodeOnlyFormatter prints only the contents of fenced code blocks,
+// or the whole answer when it has none
+type CodeOnlyFormatter struct{}
+
+func (CodeOnlyFormatter) Format(content string) string {
+	blocks := codeBlocks(content)
+	if len(blocks) == 0 {
+		return PlainFormatter{}.Format(content)
+	}
+	return strings.Join(blocks, "\n\n") + "\n"
+}
+
+// DiffFormatter prints a colored unified diff from an earlier answer to the new one,
+// for --diff
+type DiffFormatter struct {
+	Previous string
+}
+
+func (f DiffFormatter) Format(content string) string {
+	diff := utils.UnifiedDiff(f.Previous, content, "previous", "revised")
+	if diff == "" {
+		return "No changes.\n"
+	}
+
+	var out strings.Builder
+	for _, line := range strings.SplitAfter(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "---"), strings.HasPrefix(line, "+++"):
+			out.WriteString(color.New(color.Bold).Sprint(line))
+		case strings.HasPrefix(line, "@@"):
+			out.WriteString(color.CyanString("%s", line))
+		case strings.HasPrefix(line, "-"):
+			out.WriteString(color.RedString("%s", line))
+		case strings.HasPrefix(line, "+"):
+			out.WriteString(color.GreenString("%s", line))
+		default:
+			out.WriteString(line)
+		}
+	}
+	return out.String()
+}
+
+// formatters maps --output names to their formatter
+var formatters = map[string]Formatter{
+	"plain":     PlainFormatter{},
+	"markdown":  PlainFormatter{}, // The answers are markdown already
+	"glamour":   GlamourFormatter{},
+	"json":      JSONFormatter{},
+	"code-only": CodeOnlyFormatter{},
+}
+
+// NewFormatter returns the formatter for an --output name
+func NewFormatter(name string) (Formatter, error) {
+	formatter, ok := formatters[name]
+	if !ok {
+		names := make([]string, 0, len(formatters))
+		for n := range formatters {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown output format %q (available: %s)", name, strings.Join(names, ", "))
+	}
+	return formatter, nil
+}
+
+// CodeBlock returns the contents of the nth (1-based) fenced code block in markdown
+func CodeBlock(markdown string, n int) (string, error) {
+	blocks := codeBlocks(markdown)
+	if len(blocks) == 0 {
+		return "", fmt.Errorf("the answer has no code blocks")
+	}
+	if n < 1 || n > len(blocks) {
+		return "", fmt.Errorf("code block %d doesn't exist, the answer has %d", n, len(blocks))
+	}
+	return blocks[n-1], nil
+}
+
+// numberCodeBlocks labels each fenced code block with the index CodeBlock uses
+func numberCodeBlocks(markdown string) string {
+	var result []string
+	inBlock := false
+	index := 0
+
+	for _, line := range strings.Split(markdown, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			if !inBlock {
+				index++
+				result = append(result, "", fmt.Sprintf("**[%d]**", index), "")
+			}
+			inBlock = !inBlock
+		}
+		result = append(result, line)
+	}
+	return strings.Join(result, "\n")
+}
+
+// codeBlocks returns the contents of every fenced code block in markdown
+func codeBlocks(markdown string) []string {
+	var blocks []string
+	var current []string
+	inBlock := false
+
+	for _, line := range strings.Split(markdown, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			if inBlock {
+				blocks = append(blocks, strings.Join(current, "\n"))
+				current = nil
+			}
+			inBlock = !inBlock
+			continue
+		}
+		if inBlock {
+			current = append(current, line)
+		}
+	}
+	return blocks
+}
@@ -0,0 +1,178 @@
+package terminal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"ask_terminal/common"
+	"ask_terminal/config"
+	"ask_terminal/relay"
+	"ask_terminal/utils"
+)
+
+const (
+	historySearchResults  = 10  // Matches printed by --history-search
+	historyEmbeddingBatch = 100 // Queries embedded per request
+)
+
+// HistoryMatch is a history entry ranked by similarity to a search
+type HistoryMatch struct {
+	Item  utils.CommandHistoryItem
+	Score float64
+}
+
+// historyEmbeddingCache stores history query embeddings keyed by query text
+type historyEmbeddingCache struct {
+	Model   string               `json:"model"`
+	Vectors map[string][]float32 `json:"vectors"`
+}
+
+// StartHistorySearch prints the history entries closest in meaning to query
+func StartHistorySearch(query string, conf *config.Config) {
+	if query == "" {
+		fmt.Println("Error: --history-search requires a query")
+		os.Exit(common.ExitGeneric)
+	}
+
+	adapter, err := relay.NewAdapter(conf)
+	if err != nil {
+		fmt.Printf("Error initializing AI adapter: %v\n", err)
+		os.Exit(common.ExitConfig)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
+	defer cancel()
+
+	matches, err := SearchHistory(ctx, query, conf, adapter)
+	if err != nil {
+		fmt.Printf("Error searching history: %v\n", err)
+		os.Exit(ExitCodeFor(err))
+	}
+	if len(matches) == 0 {
+		fmt.Println("No command history found.")
+		return
+	}
+
+	for i, match := range matches {
+		fmt.Printf("%d. [%.2f] [%s] Query: %s\n", i+1, match.Score, match.Item.Timestamp, match.Item.Query)
+		for cmd, desc := range match.Item.Commands {
+			fmt.Printf("   - Command: %s\n     Description: %s\n", cmd, desc)
+		}
+		fmt.Println()
+	}
+}
+
+// SearchHistory ranks command history by cosine similarity between the
+// embeddings of query and each past query, best matches first
+func SearchHistory(ctx context.Context, query string, conf *config.Config, adapter relay.Adapter) ([]HistoryMatch, error) {
+	logger := utils.NewLogger()
+	items, err := logger.GetRecentCommands(utils.DefaultHistoryLimit)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+
+	model := conf.EmbeddingModelName()
+	cache := loadHistoryEmbeddings(logger.HistoryEmbeddingsPath, model)
+
+	// Embed only queries that aren't cached yet
+	var missing []string
+	wanted := make(map[string]bool)
+	for _, item := range items {
+		if _, ok := cache.Vectors[item.Query]; !ok && !wanted[item.Query] {
+			missing = append(missing, item.Query)
+		}
+		wanted[item.Query] = true
+	}
+	for start := 0; start < len(missing); start += historyEmbeddingBatch {
+		end := min(start+historyEmbeddingBatch, len(missing))
+		vectors, err := adapter.Embeddings(ctx, missing[start:end], model)
+		if err != nil {
+			return nil, err
+		}
+		for i, vector := range vectors {
+			cache.Vectors[missing[start+i]] = vector
+		}
+	}
+
+	// Forget queries that have left the history
+	for cached := range cache.Vectors {
+		if !wanted[cached] {
+			delete(cache.Vectors, cached)
+		}
+	}
+	// The embeddings are derived from the history, so they aren't kept without logging
+	if logger.Enabled {
+		if err := saveHistoryEmbeddings(logger.HistoryEmbeddingsPath, cache); err != nil {
+			utils.LogError("Failed to save history embeddings", err)
+		}
+	}
+
+	queryVectors, err := adapter.Embeddings(ctx, []string{query}, model)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make([]HistoryMatch, 0, len(items))
+	for _, item := range items {
+		matches = append(matches, HistoryMatch{
+			Item:  item,
+			Score: cosineSimilarity(queryVectors[0], cache.Vectors[item.Query]),
+		})
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	if len(matches) > historySearchResults {
+		matches = matches[:historySearchResults]
+	}
+	return matches, nil
+}
+
+// loadHistoryEmbeddings reads the embedding cache, starting over when it's
+// missing, unreadable or was built with another model
+func loadHistoryEmbeddings(path string, model string) *historyEmbeddingCache {
+	cache := &historyEmbeddingCache{}
+	if data, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, cache); err != nil {
+			cache = &historyEmbeddingCache{}
+		}
+	}
+	if cache.Model != model || cache.Vectors == nil {
+		cache = &historyEmbeddingCache{Model: model, Vectors: make(map[string][]float32)}
+	}
+	return cache
+}
+
+// saveHistoryEmbeddings writes the embedding cache
+func saveHistoryEmbeddings(path string, cache *historyEmbeddingCache) error {
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// cosineSimilarity returns the cosine of the angle between two vectors, 0 when either is empty
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
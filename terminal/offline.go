@@ -0,0 +1,75 @@
+package terminal
+
+import (
+	"fmt"
+	"os"
+
+	"ask_terminal/config"
+	"ask_terminal/utils"
+
+	"github.com/fatih/color"
+)
+
+// cacheResponse keeps an answer for offline_fallback, unless logging is off
+// (--no-log or private mode)
+func cacheResponse(conf *config.Config, mode string, query string, content string) {
+	logger := utils.NewLogger()
+	if !conf.OfflineFallback || !logger.Enabled {
+		return
+	}
+	key := utils.ResponseCacheKey(conf.ModelFor(mode), mode, query)
+	if err := logger.SaveCachedResponse(key, content); err != nil {
+		utils.LogError("Failed to cache response", err)
+	}
+}
+
+// cachedFallback returns the cached answer for a query that failed with a network
+// error, with a notice saying when it was saved
+func cachedFallback(conf *config.Config, mode string, query string, err error) (content string, notice string, ok bool) {
+	if !conf.OfflineFallback || !isNetworkError(err) {
+		return "", "", false
+	}
+	key := utils.ResponseCacheKey(conf.ModelFor(mode), mode, query)
+	cached, ok := utils.NewLogger().LoadCachedResponse(key)
+	if !ok {
+		return "", "", false
+	}
+	utils.LogInfo(fmt.Sprintf("Serving cached response for %q after network error: %v", query, err))
+	return cached.Content, fmt.Sprintf("(cached, possibly outdated: saved %s, the network request failed)",
+		cached.Saved.Format("2006-01-02 15:04")), true
+}
+
+// cachedSuggestions parses the cached suggestions for a query that failed with a network error
+func cachedSuggestions(query string, conf *config.Config, err error) ([]CommandSuggestion, bool) {
+	content, notice, ok := cachedFallback(conf, "terminal", query, err)
+	if !ok {
+		return nil, false
+	}
+	suggestions, parseErr := parseCommandSuggestions(content)
+	if parseErr != nil || len(suggestions) == 0 {
+		return nil, false
+	}
+	for i := range suggestions {
+		suggestions[i].Notice = notice
+	}
+	return suggestions, true
+}
+
+// printCachedAnswer prints a cached chat answer after its notice; the notice goes
+// to stderr so formatted output such as --output json stays parseable
+func printCachedAnswer(conf *config.Config, formatter Formatter, content string, notice string) {
+	fmt.Fprintln(os.Stderr, color.YellowString(notice))
+	if formatter != nil {
+		fmt.Print(formatter.Format(content))
+		return
+	}
+	if conf.CodeBlockIndex {
+		content = numberCodeBlocks(content)
+	}
+	rendered, err := renderMarkdown(content)
+	if err != nil {
+		fmt.Println(content)
+		return
+	}
+	fmt.Println(rendered)
+}
@@ -0,0 +1,69 @@
+package terminal
+
+import (
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/glamour"
+	"golang.org/x/term"
+)
+
+const (
+	defaultRenderWidth = 80  // Used when the terminal width can't be detected
+	maxRenderWidth     = 120 // Keep prose readable on very wide terminals
+)
+
+// terminalWidth returns the width of stdout, falling back to defaultRenderWidth.
+// It's queried on every render rather than cached, so a render done after a
+// long stream picks up any resize (SIGWINCH) that happened while streaming.
+func terminalWidth() int {
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 {
+		return defaultRenderWidth
+	}
+	return width
+}
+
+// markdownWrapWidth picks the word-wrap width for a markdown document.
+// Prose is wrapped to the terminal, but tables that don't fit get enough
+// room to keep their borders intact instead of being broken mid-cell.
+func markdownWrapWidth(markdown string) int {
+	width := terminalWidth()
+	if width > maxRenderWidth {
+		width = maxRenderWidth
+	}
+
+	if tableWidth := widestTableLine(markdown); tableWidth > width {
+		// Leave room for glamour's document margins
+		return tableWidth + 4
+	}
+	return width
+}
+
+// widestTableLine returns the length of the longest markdown table row, or 0 without tables
+func widestTableLine(markdown string) int {
+	widest := 0
+	inCodeBlock := false
+	for _, line := range strings.Split(markdown, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inCodeBlock = !inCodeBlock
+			continue
+		}
+		if inCodeBlock || !strings.HasPrefix(trimmed, "|") || strings.Count(trimmed, "|") < 2 {
+			continue
+		}
+		if n := len([]rune(trimmed)); n > widest {
+			widest = n
+		}
+	}
+	return widest
+}
+
+// newMarkdownRenderer creates a glamour renderer sized for the given document
+func newMarkdownRenderer(markdown string) (*glamour.TermRenderer, error) {
+	return glamour.NewTermRenderer(
+		glamour.WithAutoStyle(),
+		glamour.WithWordWrap(markdownWrapWidth(markdown)),
+	)
+}
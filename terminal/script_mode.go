@@ -0,0 +1,130 @@
+package terminal
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"ask_terminal/common"
+	"ask_terminal/config"
+	"ask_terminal/relay"
+	"ask_terminal/utils"
+
+	"github.com/fatih/color"
+)
+
+// StartScriptMode asks the AI for a complete shell script and optionally saves it
+func StartScriptMode(query string, conf *config.Config, outputPath string) {
+	if query == "" {
+		fmt.Println("Error: --script requires a query describing the task")
+		os.Exit(1)
+	}
+
+	utils.LogUserRequest(query, "script")
+
+	adapter, err := relay.NewAdapter(conf)
+	if err != nil {
+		fmt.Printf("Error initializing AI adapter: %v\n", err)
+		utils.LogError("Error initializing AI adapter", err)
+		os.Exit(common.ExitConfig)
+	}
+
+	request := utils.BuildPrompt(query, conf, "script")
+
+	ctx, cancel := utils.RequestContext(conf)
+	defer cancel()
+
+	printStatus(conf, "Generating script...")
+
+	response, err := adapter.ChatCompletion(ctx, request)
+	if err != nil {
+		fmt.Printf("Error communicating with AI: %v\n", err)
+		utils.LogError("Error communicating with AI", err)
+		os.Exit(ExitCodeFor(err))
+	}
+	content, err := responseContent(response)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(ExitCodeFor(err))
+	}
+
+	script := extractScript(content)
+	utils.LogSystemResponse(len(script), true, script)
+
+	// Render with syntax highlighting through a fenced markdown block
+	rendered, err := renderMarkdown("```" + scriptLanguage(script) + "\n" + script + "\n```")
+	if err != nil {
+		rendered = script
+	}
+	fmt.Println(rendered)
+	printUsage(conf, utils.UsageFor(request.Model, &response.Usage, request, content))
+
+	if outputPath == "" {
+		outputPath = askScriptPath()
+		if outputPath == "" {
+			return
+		}
+	}
+
+	if err := saveScript(outputPath, script); err != nil {
+		fmt.Println(color.RedString("Error saving script: %v", err))
+		utils.LogError("Error saving script", err)
+		os.Exit(1)
+	}
+	fmt.Println(color.GreenString("Script saved to %s", outputPath))
+}
+
+// extractScript returns the contents of the first fenced code block, or the whole answer
+func extractScript(content string) string {
+	start := strings.Index(content, "```")
+	if start == -1 {
+		return strings.TrimSpace(content)
+	}
+
+	// Skip the language tag on the opening fence
+	body := content[start+3:]
+	if newline := strings.Index(body, "\n"); newline != -1 {
+		body = body[newline+1:]
+	}
+
+	if end := strings.Index(body, "```"); end != -1 {
+		body = body[:end]
+	}
+	return strings.TrimSpace(body)
+}
+
+// scriptLanguage guesses the highlighting language from the shebang
+func scriptLanguage(script string) string {
+	firstLine := strings.SplitN(script, "\n", 2)[0]
+	switch {
+	case strings.Contains(firstLine, "python"):
+		return "python"
+	case strings.Contains(firstLine, "pwsh"), strings.Contains(firstLine, "powershell"):
+		return "powershell"
+	default:
+		return "bash"
+	}
+}
+
+// askScriptPath offers to save the script, returning an empty path when declined
+func askScriptPath() string {
+	fmt.Print("Save script to file (leave empty to skip): ")
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(answer)
+}
+
+// saveScript writes the script to path and marks it executable
+func saveScript(path string, script string) error {
+	if !strings.HasSuffix(script, "\n") {
+		script += "\n"
+	}
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		return err
+	}
+	// WriteFile keeps the mode of existing files, so chmod explicitly
+	return os.Chmod(path, 0755)
+}
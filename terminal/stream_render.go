@@ -0,0 +1,96 @@
+package terminal
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// incrementalRenderer prints a streamed markdown answer raw, then replaces each
+// paragraph with its glamour rendering once a blank line completes it. Only the
+// paragraph in progress is ever shown raw, so long answers aren't re-rendered
+// from the start on every token.
+type incrementalRenderer struct {
+	pending strings.Builder // raw text printed since the last rendered paragraph
+}
+
+// Write prints a streamed chunk and renders any paragraphs it completes
+func (r *incrementalRenderer) Write(chunk string) {
+	fmt.Print(chunk)
+	r.pending.WriteString(chunk)
+
+	raw := r.pending.String()
+	boundary := paragraphBoundary(raw)
+	if boundary == -1 {
+		return
+	}
+
+	r.erase(raw)
+	r.printRendered(raw[:boundary])
+
+	// Reprint the start of the next paragraph raw
+	rest := raw[boundary:]
+	fmt.Print(rest)
+	r.pending.Reset()
+	r.pending.WriteString(rest)
+	os.Stdout.Sync()
+}
+
+// Flush renders the final paragraph
+func (r *incrementalRenderer) Flush() {
+	raw := r.pending.String()
+	r.pending.Reset()
+	if strings.TrimSpace(raw) == "" {
+		fmt.Println()
+		return
+	}
+	r.erase(raw)
+	r.printRendered(raw)
+}
+
+// printRendered prints markdown through glamour, or as-is when rendering fails
+func (r *incrementalRenderer) printRendered(markdown string) {
+	rendered, err := renderMarkdown(markdown)
+	if err != nil {
+		fmt.Print(markdown)
+		return
+	}
+	fmt.Print(strings.Trim(rendered, "\n") + "\n\n")
+}
+
+// erase clears the raw text printed for the pending paragraph
+func (r *incrementalRenderer) erase(raw string) {
+	lines := visualLines(raw, terminalWidth())
+	fmt.Print("\r")
+	if lines > 1 {
+		fmt.Printf("\033[%dA", lines-1)
+	}
+	fmt.Print("\033[J")
+}
+
+// paragraphBoundary returns the offset just past the last blank line that
+// isn't inside a code block, or -1 when no paragraph is complete yet
+func paragraphBoundary(text string) int {
+	for end := strings.LastIndex(text, "\n\n"); end != -1; end = strings.LastIndex(text[:end], "\n\n") {
+		if strings.Count(text[:end], "```")%2 == 0 && strings.TrimSpace(text[:end]) != "" {
+			return end + 2
+		}
+	}
+	return -1
+}
+
+// visualLines counts the terminal rows text occupies when wrapped at width
+func visualLines(text string, width int) int {
+	rows := 0
+	for _, line := range strings.Split(text, "\n") {
+		lineWidth := lipgloss.Width(line)
+		if lineWidth == 0 {
+			rows++
+			continue
+		}
+		rows += (lineWidth + width - 1) / width
+	}
+	return rows
+}
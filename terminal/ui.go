@@ -1,32 +1,88 @@
 package terminal
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
-	"runtime"
+	"strings"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/fatih/color"
 
 	"ask_terminal/config"
 	"ask_terminal/dto"
+	"ask_terminal/relay"
 	"ask_terminal/utils"
 )
 
-// CommandOption represents a single command suggestion
-type CommandOption struct {
-	Command     string
-	Description string
+// ErrNoContent is returned when the model answers without any content
+var ErrNoContent = errors.New("the model returned no content; this may be a content filter or provider issue")
+
+// ErrEmptyQuery is returned instead of sending a request with no user content
+var ErrEmptyQuery = errors.New(`no query given; usage: ask [options] "query" (see ask -h)`)
+
+// ContentFilteredError reports that the provider filtered or refused the request
+type ContentFilteredError struct {
+	Reason string // Refusal text or finish reason supplied by the provider, if any
+}
+
+func (e *ContentFilteredError) Error() string {
+	if e.Reason == "" {
+		return "the provider filtered this request under its content policy"
+	}
+	return "the provider filtered this request under its content policy: " + e.Reason
+}
+
+// responseContent returns the text of the first choice, or ErrNoContent after
+// logging the raw response when the model returned nothing usable
+func responseContent(response *dto.OpenAITextResponse) (string, error) {
+	if response != nil && len(response.Choices) > 0 {
+		choice := response.Choices[0]
+		if choice.Message.Refusal != "" {
+			return "", &ContentFilteredError{Reason: choice.Message.Refusal}
+		}
+		if choice.FinishReason == dto.FinishReasonContentFilter {
+			return "", &ContentFilteredError{}
+		}
+		if content := choice.Message.StringContent(); content != "" {
+			return content, nil
+		}
+	}
+
+	raw, err := json.Marshal(response)
+	if err != nil {
+		raw = []byte(fmt.Sprintf("%+v", response))
+	}
+	utils.LogError("Model returned no content, raw response: "+string(raw), nil)
+
+	return "", ErrNoContent
+}
+
+// retryWithoutStream asks once more without streaming after a stream ended with no
+// content, returning "" when that fails too
+func retryWithoutStream(ctx context.Context, adapter relay.Adapter, request *dto.GeneralOpenAIRequest) string {
+	utils.LogInfo("Streamed answer was empty, retrying once without streaming")
+
+	request.Stream = false
+	response, err := adapter.ChatCompletion(ctx, request)
+	if err != nil {
+		utils.LogError("Retry after an empty stream failed", err)
+		return ""
+	}
+	content, err := responseContent(response)
+	if err != nil {
+		utils.LogError("Retry after an empty stream returned no answer", err)
+		return ""
+	}
+	return content
 }
 
 // ExecuteCommand runs a shell command
 func ExecuteCommand(command string) error {
-	var cmd *exec.Cmd
-	if runtime.GOOS == "windows" {
-		cmd = exec.Command("cmd", "/C", command)
-	} else {
-		cmd = exec.Command("bash", "-c", command)
-	}
+	cmd := shellCommand(command)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -34,9 +90,18 @@ func ExecuteCommand(command string) error {
 	return cmd.Run()
 }
 
+// shellCommand wraps a command line in the user's shell, the one suggestions are written for
+func shellCommand(command string) *exec.Cmd {
+	return utils.ShellCommand(utils.Shell(), command)
+}
+
 // LogCommand logs command to history file
 func LogCommand(query string, command string) {
-	historyFile := "/tmp/askta_Chistory.log"
+	logger := utils.NewLogger()
+	if !logger.Enabled {
+		return
+	}
+	historyFile := logger.CommandHistoryPath
 	entry := fmt.Sprintf("%s|%s\n", query, command)
 
 	f, err := os.OpenFile(historyFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
@@ -52,6 +117,41 @@ func LogCommand(query string, command string) {
 	}
 }
 
+// printStatus prints a status line unless show_status is disabled
+func printStatus(conf *config.Config, format string, args ...any) {
+	if !conf.StatusEnabled() {
+		return
+	}
+	fmt.Printf(format+"\n", args...)
+}
+
+// printUsage prints the token usage and estimated cost as a dim footer on stderr,
+// so it stays out of piped output, when show_usage is on
+func printUsage(conf *config.Config, usage utils.TokenUsage) {
+	if !conf.ShowUsage {
+		return
+	}
+	fmt.Fprintln(os.Stderr, color.New(color.Faint).Sprint(usage.Summary()))
+}
+
+// RenderCitations formats the sources of an answer as numbered footnotes
+func RenderCitations(citations []dto.Citation) string {
+	if len(citations) == 0 {
+		return ""
+	}
+
+	var s strings.Builder
+	s.WriteString("\nSources:\n")
+	for i, citation := range citations {
+		if citation.Title != "" {
+			s.WriteString(fmt.Sprintf("  [%d] %s - %s\n", i+1, citation.Title, citation.URL))
+		} else {
+			s.WriteString(fmt.Sprintf("  [%d] %s\n", i+1, citation.URL))
+		}
+	}
+	return s.String()
+}
+
 // RenderTitle creates a styled title for terminal UIs
 func RenderTitle(title string) string {
 	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#FAFAFA"))
@@ -72,6 +172,12 @@ func RenderError(err error) string {
 	if err == nil {
 		return ""
 	}
+	// Filtered requests aren't failures, so keep the message calm
+	var filtered *ContentFilteredError
+	if errors.As(err, &filtered) {
+		noteStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#FFAA00"))
+		return noteStyle.Render(fmt.Sprintf("Note: %v\n", err))
+	}
 	errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#FF0000"))
 	return errorStyle.Render(fmt.Sprintf("Error: %v\n", err))
 }
@@ -85,7 +191,7 @@ func RenderHelpText(text string) string {
 // BuildTerminalModePrompt creates a prompt for terminal mode
 func BuildTerminalModePrompt(query string, conf *config.Config) *dto.GeneralOpenAIRequest {
 	return &dto.GeneralOpenAIRequest{
-		Model: conf.ModelName,
+		Model: conf.ModelFor("terminal"),
 		Messages: []dto.Message{
 			{
 				Role:    "system",
@@ -105,7 +211,7 @@ func BuildTerminalModePrompt(query string, conf *config.Config) *dto.GeneralOpen
 // BuildConversationModePrompt creates a prompt for conversation mode
 func BuildConversationModePrompt(query string, conf *config.Config) *dto.GeneralOpenAIRequest {
 	return &dto.GeneralOpenAIRequest{
-		Model: conf.ModelName,
+		Model: conf.ModelFor("chat"),
 		Messages: []dto.Message{
 			{
 				Role:    "system",
@@ -0,0 +1,297 @@
+package update
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// releasesURL is the GitHub API endpoint for the latest release
+	releasesURL = "https://api.github.com/repos/keizman/Ask-Terminal-AI/releases/latest"
+)
+
+// Release holds the fields of a GitHub release used by the updater
+type Release struct {
+	TagName string  `json:"tag_name"`
+	Assets  []Asset `json:"assets"`
+}
+
+// Asset is a downloadable file attached to a release
+type Asset struct {
+	Name               string `json:"name"`
+	BrowserDownloadURL string `json:"browser_download_url"`
+}
+
+// packageManagerPaths are install locations owned by a package manager
+var packageManagerPaths = []string{
+	"/usr/bin/",
+	"/usr/share/",
+	"/opt/homebrew/",
+	"/usr/local/Cellar/",
+	"/home/linuxbrew/",
+	"/nix/store/",
+	"/snap/",
+	"/var/lib/flatpak/",
+}
+
+// Run checks for a newer release and, after confirmation, replaces the running binary
+func Run(currentVersion string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate the running binary: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(executable); err == nil {
+		executable = resolved
+	}
+
+	if manager := managedInstall(executable); manager != "" {
+		fmt.Printf("ASK Terminal AI is installed at %s, which is managed by a package manager.\n", executable)
+		fmt.Println("Please update it with your package manager instead.")
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	release, err := fetchLatestRelease(ctx)
+	if err != nil {
+		return err
+	}
+
+	latest := strings.TrimPrefix(release.TagName, "v")
+	newer, err := isNewer(latest, currentVersion)
+	if err != nil {
+		return fmt.Errorf("cannot compare release %q with version %s: %w", release.TagName, currentVersion, err)
+	}
+	if !newer {
+		fmt.Printf("ASK Terminal AI %s is up to date.\n", currentVersion)
+		return nil
+	}
+
+	asset, ok := findAsset(release.Assets, assetName())
+	if !ok {
+		return fmt.Errorf("release %s has no binary for %s/%s", release.TagName, runtime.GOOS, runtime.GOARCH)
+	}
+
+	expectedSum, err := fetchChecksum(ctx, release.Assets, asset.Name)
+	if err != nil {
+		return err
+	}
+
+	if !confirm(fmt.Sprintf("Update ASK Terminal AI from %s to %s? [y/N] ", currentVersion, latest)) {
+		fmt.Println("Update cancelled.")
+		return nil
+	}
+
+	data, err := download(ctx, asset.BrowserDownloadURL)
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(data)
+	if !strings.EqualFold(hex.EncodeToString(sum[:]), expectedSum) {
+		return fmt.Errorf("checksum mismatch for %s, refusing to install", asset.Name)
+	}
+
+	if err := replaceBinary(executable, data); err != nil {
+		return err
+	}
+
+	fmt.Printf("Updated ASK Terminal AI to %s.\n", latest)
+	return nil
+}
+
+// managedInstall reports the package manager path prefix owning the binary, if any
+func managedInstall(executable string) string {
+	for _, prefix := range packageManagerPaths {
+		if strings.HasPrefix(executable, prefix) {
+			return prefix
+		}
+	}
+	return ""
+}
+
+// assetName returns the release asset name for the current platform
+func assetName() string {
+	if runtime.GOOS == "windows" {
+		return "ask.exe"
+	}
+	if runtime.GOARCH == "amd64" {
+		return "ask_" + runtime.GOOS
+	}
+	return "ask_" + runtime.GOOS + "_" + runtime.GOARCH
+}
+
+// findAsset looks up a release asset by name
+func findAsset(assets []Asset, name string) (Asset, bool) {
+	for _, asset := range assets {
+		if asset.Name == name {
+			return asset, true
+		}
+	}
+	return Asset{}, false
+}
+
+// fetchLatestRelease queries the GitHub API for the latest release
+func fetchLatestRelease(ctx context.Context) (*Release, error) {
+	data, err := download(ctx, releasesURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for updates: %w", err)
+	}
+
+	var release Release
+	if err := json.Unmarshal(data, &release); err != nil {
+		return nil, fmt.Errorf("failed to parse release information: %w", err)
+	}
+	return &release, nil
+}
+
+// fetchChecksum finds the expected SHA-256 of an asset from a checksum file in the release
+func fetchChecksum(ctx context.Context, assets []Asset, name string) (string, error) {
+	for _, candidate := range []string{name + ".sha256", "checksums.txt", "sha256sums.txt"} {
+		asset, ok := findAsset(assets, candidate)
+		if !ok {
+			continue
+		}
+
+		data, err := download(ctx, asset.BrowserDownloadURL)
+		if err != nil {
+			return "", fmt.Errorf("failed to download checksum file: %w", err)
+		}
+
+		// Lines look like "<sha256>  <file name>", or just "<sha256>" for single-file checksums
+		for _, line := range strings.Split(string(data), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) == 1 && candidate == name+".sha256" {
+				return fields[0], nil
+			}
+			if len(fields) >= 2 && strings.TrimPrefix(fields[1], "*") == name {
+				return fields[0], nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("no checksum published for %s, refusing to install an unverified binary", name)
+}
+
+// download fetches a URL and returns the response body
+func download(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: status code %d", url, resp.StatusCode)
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+// replaceBinary atomically swaps the binary at path with data
+func replaceBinary(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, ".ask-update-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file in %s: %w", dir, err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write new binary: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write new binary: %w", err)
+	}
+	if err := os.Chmod(tmpPath, 0755); err != nil {
+		return fmt.Errorf("failed to make new binary executable: %w", err)
+	}
+
+	// Windows can't overwrite a running executable, but it can rename it
+	if runtime.GOOS == "windows" {
+		oldPath := path + ".old"
+		_ = os.Remove(oldPath)
+		if err := os.Rename(path, oldPath); err != nil {
+			return fmt.Errorf("failed to move old binary aside: %w", err)
+		}
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace binary: %w", err)
+	}
+	return nil
+}
+
+// confirm asks a yes/no question on stdin, defaulting to no
+func confirm(question string) bool {
+	fmt.Print(question)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// isNewer reports whether version a is newer than version b (dotted numeric versions)
+func isNewer(a, b string) (bool, error) {
+	partsA, err := parseVersion(a)
+	if err != nil {
+		return false, err
+	}
+	partsB, err := parseVersion(b)
+	if err != nil {
+		return false, err
+	}
+
+	for i := 0; i < len(partsA) || i < len(partsB); i++ {
+		var x, y int
+		if i < len(partsA) {
+			x = partsA[i]
+		}
+		if i < len(partsB) {
+			y = partsB[i]
+		}
+		if x != y {
+			return x > y, nil
+		}
+	}
+	return false, nil
+}
+
+// parseVersion splits a version such as "1.2.3" into its numeric parts
+func parseVersion(version string) ([]int, error) {
+	// Ignore pre-release and build metadata
+	version = strings.SplitN(version, "-", 2)[0]
+	version = strings.SplitN(version, "+", 2)[0]
+
+	var parts []int
+	for _, field := range strings.Split(version, ".") {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version %q", version)
+		}
+		parts = append(parts, n)
+	}
+	return parts, nil
+}
@@ -0,0 +1,155 @@
+package utils
+
+import (
+	"ask_terminal/config"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// bugReportLogLines is how much of the application log a bug report includes
+	bugReportLogLines = 40
+	// bugReportErrorLines is how far back the log is searched for the last error
+	bugReportErrorLines = 1000
+)
+
+// secretPatterns match credentials that may show up in logs or prompts
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`sk-[A-Za-z0-9_\-]{8,}`),
+	regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._\-]+`),
+	regexp.MustCompile(`(?i)((?:api[_-]?key|token|secret|password)["']?\s*[:=]\s*["']?)[^\s"',]+`),
+	regexp.MustCompile(`(://[^/\s:@]+:)[^@\s/]+@`),
+}
+
+// RedactSecrets replaces API keys, tokens and URL passwords in text
+func RedactSecrets(text string) string {
+	for _, pattern := range secretPatterns {
+		if pattern.NumSubexp() > 0 {
+			text = pattern.ReplaceAllString(text, "${1}[REDACTED]")
+		} else {
+			text = pattern.ReplaceAllString(text, "[REDACTED]")
+		}
+	}
+	return text
+}
+
+// BuildBugReport collects version, platform, the redacted config, the last error
+// and the tail of the application log into a report that can be pasted into an issue
+func BuildBugReport(configPath string, version string) string {
+	var report strings.Builder
+	report.WriteString("## ASK Terminal AI bug report\n\n")
+
+	report.WriteString("### Version\n")
+	report.WriteString(fmt.Sprintf("- Version: %s\n", version))
+	report.WriteString(fmt.Sprintf("- Go: %s\n", runtime.Version()))
+	report.WriteString(fmt.Sprintf("- OS/Arch: %s\n", GetSystemInfo()))
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" || setting.Key == "vcs.modified" {
+				report.WriteString(fmt.Sprintf("- %s: %s\n", setting.Key, setting.Value))
+			}
+		}
+	}
+	if shell := os.Getenv("SHELL"); shell != "" {
+		report.WriteString(fmt.Sprintf("- Shell: %s\n", shell))
+	}
+
+	report.WriteString("\n### Config\n")
+	if path, err := config.ResolveConfigPath(configPath); err == nil {
+		report.WriteString(fmt.Sprintf("Path: %s\n", path))
+	}
+	report.WriteString("```yaml\n" + redactedConfig(configPath) + "```\n")
+
+	logger := NewLogger()
+	lines, err := logger.TailApplicationLog(bugReportErrorLines)
+	report.WriteString("\n### Last error\n")
+	if lastError := lastLoggedError(lines); lastError != "" {
+		report.WriteString(RedactSecrets(lastError) + "\n")
+	} else {
+		report.WriteString("(none in the recent log)\n")
+	}
+
+	report.WriteString(fmt.Sprintf("\n### Application log (%s, last %d lines)\n", logger.ApplicationLogPath, bugReportLogLines))
+	if err != nil {
+		report.WriteString(fmt.Sprintf("(unavailable: %v)\n", err))
+	} else {
+		report.WriteString("```\n")
+		if len(lines) > bugReportLogLines {
+			lines = lines[len(lines)-bugReportLogLines:]
+		}
+		for _, line := range lines {
+			// Answers can be long and private, their length is logged separately
+			if strings.Contains(line, "[RESPONSE CONTENT]") {
+				continue
+			}
+			report.WriteString(RedactSecrets(line) + "\n")
+		}
+		report.WriteString("```\n")
+	}
+
+	return report.String()
+}
+
+// redactedConfig returns the config file as YAML with keys and proxy passwords hidden
+func redactedConfig(configPath string) string {
+	conf, err := config.ReadConfigFile(configPath)
+	if err != nil {
+		return fmt.Sprintf("# could not read config: %v\n", err)
+	}
+
+	data, err := RedactedConfigYAML(conf)
+	if err != nil {
+		return fmt.Sprintf("# could not encode config: %v\n", err)
+	}
+	return data
+}
+
+// RedactedConfigYAML encodes a config as YAML with API keys and proxy passwords
+// hidden; conf itself is left unchanged
+func RedactedConfigYAML(conf *config.Config) (string, error) {
+	redacted := *conf
+	redacted.APIKey = config.RedactKey(conf.APIKey)
+	redacted.Proxy = redactURL(conf.Proxy)
+	if conf.Profiles != nil {
+		redacted.Profiles = make(map[string]config.Profile, len(conf.Profiles))
+		for name, profile := range conf.Profiles {
+			profile.APIKey = config.RedactKey(profile.APIKey)
+			redacted.Profiles[name] = profile
+		}
+	}
+
+	data, err := yaml.Marshal(&redacted)
+	if err != nil {
+		return "", err
+	}
+	return RedactSecrets(string(data)), nil
+}
+
+// redactURL hides the password of a URL such as a proxy address
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return raw
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), "REDACTED")
+	}
+	return u.String()
+}
+
+// lastLoggedError returns the most recent [ERROR] log line
+func lastLoggedError(lines []string) string {
+	for i := len(lines) - 1; i >= 0; i-- {
+		if strings.Contains(lines[i], "[ERROR]") {
+			return lines[i]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,18 @@
+package utils
+
+import (
+	"fmt"
+
+	"github.com/atotto/clipboard"
+)
+
+// CopyToClipboard writes text to the system clipboard
+func CopyToClipboard(text string) error {
+	if clipboard.Unsupported {
+		return fmt.Errorf("clipboard is not supported on this system (install xclip, xsel or wl-clipboard)")
+	}
+	if err := clipboard.WriteAll(text); err != nil {
+		return fmt.Errorf("failed to copy to clipboard: %w", err)
+	}
+	return nil
+}
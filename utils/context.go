@@ -0,0 +1,220 @@
+package utils
+
+import (
+	"ask_terminal/config"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+const (
+	// maxIncludedFileBytes caps how much of each included file is sent
+	maxIncludedFileBytes = 20 * 1024
+	// maxIncludedTotalBytes caps the contents of all included files together
+	maxIncludedTotalBytes = 100 * 1024
+)
+
+// MatchesContextExtensions reports whether a file name passes the context_extensions
+// allowlist; an empty allowlist allows every file
+func MatchesContextExtensions(name string, extensions []string) bool {
+	if len(extensions) == 0 {
+		return true
+	}
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, allowed := range extensions {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if !strings.HasPrefix(allowed, ".") {
+			allowed = "." + allowed
+		}
+		if ext == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// IncludedFiles is the context built from the include patterns
+type IncludedFiles struct {
+	Content string // Formatted file contents
+	Files   int    // Files whose contents were included
+	Bytes   int    // Bytes of file contents included
+	Skipped int    // Files left out once maxIncludedTotalBytes was reached
+}
+
+// BuildIncludedFiles reads the files matching the include patterns and formats
+// them as context, skipping files outside the extension allowlist and binaries.
+// Files are read in sorted order until maxIncludedTotalBytes is reached.
+func BuildIncludedFiles(patterns, extensions, ignore []string) (IncludedFiles, error) {
+	var included IncludedFiles
+
+	paths, err := includedFilePaths(patterns, extensions, ignore)
+	if err != nil {
+		return included, err
+	}
+
+	var result strings.Builder
+	for i, path := range paths {
+		content, err := readContextFile(path)
+		if err != nil {
+			LogError("Failed to read included file "+path, err)
+			continue
+		}
+		if included.Bytes+len(content) > maxIncludedTotalBytes {
+			included.Skipped = len(paths) - i
+			result.WriteString(fmt.Sprintf("\n... (%d more files left out, included files are limited to %d bytes)\n",
+				included.Skipped, maxIncludedTotalBytes))
+			break
+		}
+		result.WriteString("\n--- " + path + " ---\n")
+		result.WriteString(content)
+		if !strings.HasSuffix(content, "\n") {
+			result.WriteString("\n")
+		}
+		included.Files++
+		included.Bytes += len(content)
+	}
+
+	included.Content = result.String()
+	return included, nil
+}
+
+// includedFilePaths expands the include patterns to the sorted, deduplicated files
+// allowed by the extension allowlist; directories are walked recursively, skipping
+// names in the ignore list
+func includedFilePaths(patterns, extensions, ignore []string) ([]string, error) {
+	seen := make(map[string]bool)
+
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid include pattern %q: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("include pattern %q matched no files", pattern)
+		}
+
+		for _, path := range matches {
+			info, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+			if info.IsDir() {
+				walkIncludedDir(path, extensions, ignore, seen)
+				continue
+			}
+			if MatchesContextExtensions(path, extensions) {
+				seen[filepath.Clean(path)] = true
+			}
+		}
+	}
+
+	paths := make([]string, 0, len(seen))
+	for path := range seen {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// walkIncludedDir adds the allowed files under dir to seen, skipping ignored names
+func walkIncludedDir(dir string, extensions, ignore []string, seen map[string]bool) {
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil // Skip unreadable entries and continue
+		}
+		if path != dir && isIgnoredName(info.Name(), ignore) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.IsDir() && MatchesContextExtensions(path, extensions) {
+			seen[filepath.Clean(path)] = true
+		}
+		return nil
+	})
+}
+
+// isIgnoredName reports whether a file or directory name is in the ignore list
+func isIgnoredName(name string, ignore []string) bool {
+	for _, ignored := range ignore {
+		if matched, _ := filepath.Match(ignored, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// ContextSize describes how much context a request would carry
+type ContextSize struct {
+	Files int // Directory entries and included files
+	Bytes int // Size of the system prompt
+}
+
+// EstimatedTokens is a rough token count, at about four bytes per token
+func (s ContextSize) EstimatedTokens() int {
+	return s.Bytes / 4
+}
+
+// MeasureContext computes the size of the context buildSystemContext would send for mode
+func MeasureContext(conf *config.Config, mode string) ContextSize {
+	size := ContextSize{Bytes: len(buildSystemContext(conf, mode))}
+
+	if !conf.PrivateMode {
+		for _, line := range strings.Split(GetDirectoryStructure(1, conf.ContextExtensions, conf.ContextIgnoreList(), conf.DirWalkWorkerCount()), "\n") {
+			if strings.HasPrefix(strings.TrimSpace(line), "📁") || strings.HasPrefix(strings.TrimSpace(line), "📄") {
+				size.Files++
+			}
+		}
+	}
+	if paths, err := includedFilePaths(conf.Include, conf.ContextExtensions, conf.ContextIgnoreList()); err == nil {
+		size.Files += len(paths)
+	}
+
+	return size
+}
+
+// readContextFile reads a text file, truncating large files and rejecting binaries
+func readContextFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	data = decodeText(data)
+	if bytes.IndexByte(data, 0) != -1 {
+		return "", fmt.Errorf("binary file")
+	}
+	if len(data) > maxIncludedFileBytes {
+		return string(data[:maxIncludedFileBytes]) + "\n... (truncated)\n", nil
+	}
+	return string(data), nil
+}
+
+// decodeText turns a file marked with a byte order mark into plain UTF-8: the
+// UTF-8 BOM is dropped and UTF-16 text, as saved by some Windows tools, is decoded.
+// Files without a BOM are returned unchanged.
+func decodeText(data []byte) []byte {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return data[3:]
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		return decodeUTF16(data[2:], binary.LittleEndian)
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		return decodeUTF16(data[2:], binary.BigEndian)
+	}
+	return data
+}
+
+// decodeUTF16 converts UTF-16 text in the given byte order to UTF-8
+func decodeUTF16(data []byte, order binary.ByteOrder) []byte {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[2*i:])
+	}
+	return []byte(string(utf16.Decode(units)))
+}
@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"ask_terminal/config"
+	"fmt"
+	"regexp"
+)
+
+// dangerPattern pairs a regular expression with a human readable reason
+type dangerPattern struct {
+	pattern *regexp.Regexp
+	reason  string
+}
+
+// dangerPatterns lists commands that can destroy data or take the system down
+var dangerPatterns = []dangerPattern{
+	{regexp.MustCompile(`\brm\s+(-[a-zA-Z]*[rR][a-zA-Z]*\s+|--recursive\s+)`), "recursively deletes files"},
+	{regexp.MustCompile(`\brm\s+-[a-zA-Z]*f`), "force deletes files"},
+	{regexp.MustCompile(`\bmkfs(\.\w+)?\b`), "formats a filesystem"},
+	{regexp.MustCompile(`\bdd\s+.*\bof=`), "writes raw data to a file or device"},
+	{regexp.MustCompile(`>\s*/dev/(sd|hd|nvme|disk)`), "overwrites a block device"},
+	{regexp.MustCompile(`\b(shutdown|reboot|halt|poweroff)\b`), "shuts down or restarts the system"},
+	{regexp.MustCompile(`\bchmod\s+(-[a-zA-Z]*R[a-zA-Z]*\s+)?[0-7]*777\s+/`), "opens permissions on system paths"},
+	{regexp.MustCompile(`\bchown\s+-[a-zA-Z]*R`), "recursively changes ownership"},
+	{regexp.MustCompile(`:\(\)\s*\{\s*:\|:&\s*\};:`), "is a fork bomb"},
+	{regexp.MustCompile(`\b(curl|wget)\b[^|]*\|\s*(sudo\s+)?(ba|z|da|k)?sh\b`), "pipes a download straight into a shell"},
+	{regexp.MustCompile(`\bgit\s+(reset\s+--hard|clean\s+-[a-zA-Z]*f|push\s+.*--force)`), "discards or overwrites git history"},
+	{regexp.MustCompile(`\b(del|rd|rmdir)\s+/[sSqQ]`), "recursively deletes files"},
+	{regexp.MustCompile(`\bformat\s+[a-zA-Z]:`), "formats a drive"},
+}
+
+// IsDangerousCommand reports whether a command matches a built-in pattern and why
+func IsDangerousCommand(command string) (bool, string) {
+	return matchDangerPatterns(command, dangerPatterns)
+}
+
+// CheckDangerousCommand is IsDangerousCommand with the configured patterns: the
+// built-in list unless no_default_dangerous_commands is set, plus dangerous_commands
+func CheckDangerousCommand(command string, conf *config.Config) (bool, string) {
+	patterns, err := configuredDangerPatterns(conf)
+	if err != nil {
+		// ValidateDangerousCommands rejects bad patterns at startup, keep the built-in check anyway
+		LogError("Invalid dangerous_commands pattern", err)
+		return IsDangerousCommand(command)
+	}
+	return matchDangerPatterns(command, patterns)
+}
+
+// ValidateDangerousCommands reports the first dangerous_commands pattern that doesn't compile
+func ValidateDangerousCommands(conf *config.Config) error {
+	_, err := configuredDangerPatterns(conf)
+	return err
+}
+
+// configuredDangerPatterns compiles the patterns selected by the config
+func configuredDangerPatterns(conf *config.Config) ([]dangerPattern, error) {
+	var patterns []dangerPattern
+	if !conf.NoDefaultDangerousCommands {
+		patterns = append(patterns, dangerPatterns...)
+	}
+	for _, custom := range conf.DangerousCommands {
+		re, err := regexp.Compile(custom.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dangerous_commands pattern %q: %w", custom.Pattern, err)
+		}
+		reason := custom.Reason
+		if reason == "" {
+			reason = "matches a dangerous_commands pattern"
+		}
+		patterns = append(patterns, dangerPattern{pattern: re, reason: reason})
+	}
+	return patterns, nil
+}
+
+// matchDangerPatterns returns the reason of the first matching pattern
+func matchDangerPatterns(command string, patterns []dangerPattern) (bool, string) {
+	for _, p := range patterns {
+		if p.pattern.MatchString(command) {
+			return true, p.reason
+		}
+	}
+	return false, ""
+}
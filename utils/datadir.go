@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// migratedFiles are the files earlier versions kept in the temp dir; rotated copies
+// (.1, .2, ...) are moved along with them
+var migratedFiles = []string{
+	"askta_Chistory.log",
+	"askta_run.log",
+	"askta_last_response.txt",
+	"askta_last_command.txt",
+	"askta_history_embeddings.json",
+	"askta_response_cache.json",
+}
+
+var (
+	dataDirOnce sync.Once
+	dataDir     string
+)
+
+// DataDir returns the directory for the log, command history and other files kept
+// across runs: $XDG_DATA_HOME/askta (~/.local/share/askta) or %LOCALAPPDATA%\askta on
+// Windows. Files left in the temp dir by earlier versions are moved there the first
+// time. The temp dir is used only when the data dir can't be created.
+func DataDir() string {
+	dataDirOnce.Do(func() {
+		dir, err := userDataDir()
+		if err == nil {
+			err = os.MkdirAll(dir, 0700)
+		}
+		if err != nil {
+			dataDir = os.TempDir()
+			return
+		}
+		dataDir = dir
+		migrateTempFiles(os.TempDir(), dir)
+	})
+	return dataDir
+}
+
+// userDataDir returns the platform's per-user data directory for askta
+func userDataDir() (string, error) {
+	if runtime.GOOS == "windows" {
+		if dir := os.Getenv("LOCALAPPDATA"); dir != "" {
+			return filepath.Join(dir, "askta"), nil
+		}
+	} else if dir := os.Getenv("XDG_DATA_HOME"); filepath.IsAbs(dir) {
+		return filepath.Join(dir, "askta"), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	if runtime.GOOS == "windows" {
+		return filepath.Join(home, "AppData", "Local", "askta"), nil
+	}
+	return filepath.Join(home, ".local", "share", "askta"), nil
+}
+
+// migrateTempFiles moves files from the temp dir into dir, never overwriting a file
+// already there. Failures leave the old file in place.
+func migrateTempFiles(tempDir, dir string) {
+	for _, name := range migratedFiles {
+		matches, _ := filepath.Glob(filepath.Join(tempDir, name+"*"))
+		for _, oldPath := range matches {
+			base := filepath.Base(oldPath)
+			if strings.HasSuffix(base, ".lock") {
+				continue
+			}
+			newPath := filepath.Join(dir, base)
+			if _, err := os.Stat(newPath); err == nil {
+				continue
+			}
+			// Not logged: the log itself may be among the files being moved
+			moveFile(oldPath, newPath)
+		}
+	}
+}
+
+// moveFile renames a file, copying it when the temp dir is on another file system
+func moveFile(oldPath, newPath string) error {
+	if err := os.Rename(oldPath, newPath); err == nil {
+		return nil
+	}
+
+	src, err := os.Open(oldPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(newPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(newPath)
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(newPath)
+		return err
+	}
+	src.Close()
+	return os.Remove(oldPath)
+}
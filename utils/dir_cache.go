@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dirCacheTTL is how long a rendered directory tree is reused
+const dirCacheTTL = 10 * time.Second
+
+// dirCacheEntry is a rendered tree and the state of the directory it was built from
+type dirCacheEntry struct {
+	tree    string
+	modTime time.Time
+	saved   time.Time
+}
+
+// dirCache holds rendered trees for repeated prompts in the same process, such as
+// conversation follow-ups
+var dirCache = struct {
+	sync.Mutex
+	entries map[string]dirCacheEntry
+}{entries: make(map[string]dirCacheEntry)}
+
+// dirCacheKey identifies a tree by directory and the options that shape it
+func dirCacheKey(dir string, maxDepth int, extensions []string, ignore []string) string {
+	return dir + "\x00" + strconv.Itoa(maxDepth) + "\x00" + strings.Join(extensions, ",") + "\x00" + strings.Join(ignore, ",")
+}
+
+// cachedDirectoryTree returns a tree rendered within the TTL, as long as the directory's
+// mtime hasn't changed since (adding or removing an entry updates it)
+func cachedDirectoryTree(key string, dir string) (string, bool) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return "", false
+	}
+
+	dirCache.Lock()
+	defer dirCache.Unlock()
+	entry, ok := dirCache.entries[key]
+	if !ok || time.Since(entry.saved) > dirCacheTTL || !entry.modTime.Equal(info.ModTime()) {
+		delete(dirCache.entries, key)
+		return "", false
+	}
+	return entry.tree, true
+}
+
+// storeDirectoryTree remembers a rendered tree with the directory's current mtime
+func storeDirectoryTree(key string, dir string, tree string) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return
+	}
+
+	dirCache.Lock()
+	defer dirCache.Unlock()
+	dirCache.entries[key] = dirCacheEntry{tree: tree, modTime: info.ModTime(), saved: time.Now()}
+}
@@ -2,11 +2,11 @@ package utils
 
 import (
 	"os"
-	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strconv" // Add this import
 	"strings"
+	"sync"
 )
 
 // GetSystemInfo returns information about the operating system
@@ -15,10 +15,9 @@ func GetSystemInfo() string {
 	return runtime.GOOS + " - " + runtime.GOARCH
 }
 
-// GetDirectoryStructure returns a string representation of the current directory structure
-// with improved performance for large directories
-// This is the canonical implementation
-func GetDirectoryStructure(maxDepth int) string {
+// GetDirectoryStructure returns the tree of the current directory down to maxDepth,
+// skipping ignored and .gitignore'd paths and files outside the extensions allowlist
+func GetDirectoryStructure(maxDepth int, extensions []string, ignore []string, workers int) string {
 	if maxDepth <= 0 {
 		maxDepth = 2
 	}
@@ -28,60 +27,118 @@ func GetDirectoryStructure(maxDepth int) string {
 		return "Error getting current directory"
 	}
 
+	cacheKey := dirCacheKey(cwd, maxDepth, extensions, ignore)
+	if tree, ok := cachedDirectoryTree(cacheKey, cwd); ok {
+		return tree
+	}
+
+	skip := treeSkipper(cwd, ignore)
+	listings := listDirectories(cwd, maxDepth, workers, skip)
+	if _, ok := listings[cwd]; !ok {
+		return "Error reading directory structure"
+	}
+
 	var result strings.Builder
 	fileCount := 0
 	maxFiles := 100 // Limit to prevent excessive output
+	writeDirectoryTree(&result, listings, cwd, 1, extensions, skip, &fileCount, maxFiles)
 
-	err = filepath.Walk(cwd, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil // Skip errors and continue
-		}
+	// Add a message if we hit the file limit
+	if fileCount >= maxFiles {
+		// Fix the integer to string conversion using strconv.Itoa
+		result.WriteString("\n... (output limited to " + strconv.Itoa(maxFiles) + " entries)\n")
+	}
 
-		// Stop if we've reached the file limit
-		if fileCount >= maxFiles {
-			return filepath.SkipDir
-		}
+	storeDirectoryTree(cacheKey, cwd, result.String())
+	return result.String()
+}
 
-		// Calculate depth
-		relPath, err := filepath.Rel(cwd, path)
-		if err != nil {
-			return nil
-		}
+// treeSkipper returns a function reporting whether an entry of the tree under root is
+// left out, by name or by the nearest .gitignore
+func treeSkipper(root string, ignore []string) func(path string, entry os.DirEntry) bool {
+	gitignore := loadNearestGitignore(root)
+	return func(path string, entry os.DirEntry) bool {
+		return isIgnoredName(entry.Name(), ignore) || gitignore.Ignored(path, entry.IsDir())
+	}
+}
 
-		if relPath == "." {
-			return nil
+// listDirectories reads root and its subdirectories down to maxDepth, one level at a
+// time with a pool of workers, returning each directory's sorted entries by path.
+// Directories for which skip returns true are not read.
+func listDirectories(root string, maxDepth int, workers int, skip func(string, os.DirEntry) bool) map[string][]os.DirEntry {
+	if workers <= 0 {
+		workers = 1
+	}
+
+	listings := make(map[string][]os.DirEntry)
+	level := []string{root}
+	for depth := 1; depth <= maxDepth && len(level) > 0; depth++ {
+		entries := make([][]os.DirEntry, len(level))
+		errs := make([]error, len(level))
+		jobs := make(chan int)
+		var wg sync.WaitGroup
+		for w := 0; w < workers && w < len(level); w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range jobs {
+					// os.ReadDir sorts by name
+					entries[i], errs[i] = os.ReadDir(level[i])
+				}
+			}()
+		}
+		for i := range level {
+			jobs <- i
 		}
+		close(jobs)
+		wg.Wait()
 
-		depth := len(strings.Split(relPath, string(os.PathSeparator)))
-		if depth > maxDepth {
-			if info.IsDir() {
-				return filepath.SkipDir // Skip deeper directories
+		var next []string
+		for i, dir := range level {
+			if errs[i] != nil {
+				continue // Skip unreadable directories and continue
+			}
+			listings[dir] = entries[i]
+			for _, entry := range entries[i] {
+				path := filepath.Join(dir, entry.Name())
+				if entry.IsDir() && !skip(path, entry) {
+					next = append(next, path)
+				}
 			}
-			return nil
 		}
+		level = next
+	}
+	return listings
+}
 
-		prefix := strings.Repeat("  ", depth-1)
-		if info.IsDir() {
-			result.WriteString(prefix + "📁 " + info.Name() + "\n")
-		} else {
-			result.WriteString(prefix + "📄 " + info.Name() + "\n")
+// writeDirectoryTree writes the listed entries under dir depth first, stopping at maxFiles entries
+func writeDirectoryTree(result *strings.Builder, listings map[string][]os.DirEntry, dir string, depth int,
+	extensions []string, skip func(string, os.DirEntry) bool, fileCount *int, maxFiles int) {
+	prefix := strings.Repeat("  ", depth-1)
+	for _, entry := range listings[dir] {
+		if *fileCount >= maxFiles {
+			return
 		}
 
-		fileCount++
-		return nil
-	})
+		// Skip files outside the context_extensions allowlist
+		if !entry.IsDir() && !MatchesContextExtensions(entry.Name(), extensions) {
+			continue
+		}
+		if skip(filepath.Join(dir, entry.Name()), entry) {
+			continue
+		}
 
-	if err != nil {
-		return "Error reading directory structure"
-	}
+		if entry.IsDir() {
+			result.WriteString(prefix + "📁 " + entry.Name() + "\n")
+		} else {
+			result.WriteString(prefix + "📄 " + entry.Name() + "\n")
+		}
+		*fileCount++
 
-	// Add a message if we hit the file limit
-	if fileCount >= maxFiles {
-		// Fix the integer to string conversion using strconv.Itoa
-		result.WriteString("\n... (output limited to " + strconv.Itoa(maxFiles) + " entries)\n")
+		if entry.IsDir() {
+			writeDirectoryTree(result, listings, filepath.Join(dir, entry.Name()), depth+1, extensions, skip, fileCount, maxFiles)
+		}
 	}
-
-	return result.String()
 }
 
 // // walkDir is a helper function to recursively walk directories
@@ -145,13 +202,7 @@ func GetDirectoryStructure(maxDepth int) string {
 
 // ExecuteCommand runs a shell command and returns the output
 func ExecuteCommand(command string) (string, error) {
-	var cmd *exec.Cmd
-	if runtime.GOOS == "windows" {
-		cmd = exec.Command("cmd", "/C", command)
-	} else {
-		cmd = exec.Command("bash", "-c", command)
-	}
-
+	cmd := ShellCommand(Shell(), command)
 	output, err := cmd.CombinedOutput()
 	return string(output), err
 }
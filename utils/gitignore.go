@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// gitignorePattern is one line of a .gitignore file
+type gitignorePattern struct {
+	pattern  string
+	negate   bool // "!pattern" re-includes a path
+	dirOnly  bool // "pattern/" matches directories only
+	anchored bool // Patterns with a slash match from the .gitignore's directory
+}
+
+// gitignore matches paths against the patterns of a single .gitignore file
+type gitignore struct {
+	base     string
+	patterns []gitignorePattern
+}
+
+// loadNearestGitignore reads the .gitignore in dir or the closest parent, stopping at
+// the repository root. It returns nil when there is none.
+func loadNearestGitignore(dir string) *gitignore {
+	for {
+		if data, err := os.ReadFile(filepath.Join(dir, ".gitignore")); err == nil {
+			return parseGitignore(dir, string(data))
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil
+		}
+		dir = parent
+	}
+}
+
+// parseGitignore parses the contents of a .gitignore file located in base
+func parseGitignore(base string, content string) *gitignore {
+	ignore := &gitignore{base: base}
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, "\r \t")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var p gitignorePattern
+		if strings.HasPrefix(line, "!") {
+			p.negate = true
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			p.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		line = strings.TrimPrefix(line, "**/")
+		if strings.Contains(line, "/") {
+			p.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		p.pattern = line
+		ignore.patterns = append(ignore.patterns, p)
+	}
+	return ignore
+}
+
+// Ignored reports whether path is ignored. As in git, the last matching pattern wins.
+func (g *gitignore) Ignored(path string, isDir bool) bool {
+	if g == nil {
+		return false
+	}
+	rel, err := filepath.Rel(g.base, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+
+	ignored := false
+	for _, p := range g.patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		if p.matches(rel) {
+			ignored = !p.negate
+		}
+	}
+	return ignored
+}
+
+// matches reports whether the slash separated path relative to the .gitignore matches
+func (p gitignorePattern) matches(rel string) bool {
+	if !p.anchored {
+		matched, _ := filepath.Match(p.pattern, rel[strings.LastIndex(rel, "/")+1:])
+		return matched
+	}
+	// "dir/**" matches everything below dir
+	if prefix, ok := strings.CutSuffix(p.pattern, "/**"); ok {
+		return strings.HasPrefix(rel, prefix+"/")
+	}
+	matched, _ := filepath.Match(p.pattern, rel)
+	return matched
+}
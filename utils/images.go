@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxImageBytes is the largest local image attached, the limit of the OpenAI API
+const maxImageBytes = 20 << 20
+
+// textOnlyModels are model name prefixes known not to accept images; other models
+// are assumed to, and the provider reports an error if they don't
+var textOnlyModels = []string{
+	"gpt-3.5",
+	"gpt-4-0",
+	"o1-mini",
+	"o3-mini",
+	"deepseek-",
+	"codestral",
+	"text-",
+}
+
+// ModelAcceptsImages reports whether a model can be sent images, ignoring a
+// "provider/" prefix as used by routers
+func ModelAcceptsImages(model string) bool {
+	model = strings.ToLower(model)
+	if slash := strings.LastIndex(model, "/"); slash != -1 {
+		model = model[slash+1:]
+	}
+	for _, prefix := range textOnlyModels {
+		if strings.HasPrefix(model, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// ImageURL returns the image_url for an --image argument: http(s) and data URLs as
+// they are, local files read and encoded as base64 data URLs
+func ImageURL(pathOrURL string) (string, error) {
+	lower := strings.ToLower(pathOrURL)
+	if strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "data:") {
+		return pathOrURL, nil
+	}
+
+	info, err := os.Stat(pathOrURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to read image: %w", err)
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("image %s is a directory", pathOrURL)
+	}
+	if info.Size() > maxImageBytes {
+		return "", fmt.Errorf("image %s is %d MB, the limit is %d MB", pathOrURL, info.Size()>>20, maxImageBytes>>20)
+	}
+
+	data, err := os.ReadFile(pathOrURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to read image: %w", err)
+	}
+	mimeType := http.DetectContentType(data)
+	if !strings.HasPrefix(mimeType, "image/") {
+		return "", fmt.Errorf("%s is not an image (detected %s)", filepath.Base(pathOrURL), mimeType)
+	}
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data), nil
+}
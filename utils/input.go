@@ -0,0 +1,126 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// ResolveQueryArgs joins command line arguments into a query. Arguments
+// starting with "@" are read from the named file, and "@-" reads stdin.
+func ResolveQueryArgs(args []string) (string, error) {
+	parts := make([]string, 0, len(args))
+	for _, arg := range args {
+		if len(arg) < 2 || !strings.HasPrefix(arg, "@") {
+			parts = append(parts, arg)
+			continue
+		}
+
+		content, err := readQueryFile(arg[1:])
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, strings.TrimRight(content, "\r\n"))
+	}
+
+	return strings.Join(parts, " "), nil
+}
+
+// readQueryFile reads a query from a file, or from stdin when path is "-"
+func readQueryFile(path string) (string, error) {
+	if path == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", fmt.Errorf("failed to read query from stdin: %w", err)
+		}
+		return string(data), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("query file not found: %s", path)
+		}
+		return "", fmt.Errorf("failed to read query file %s: %w", path, err)
+	}
+	return string(data), nil
+}
+
+// ReadAssistantDraft reads the file given to --assistant-file, or stdin for "-"
+func ReadAssistantDraft(path string) (string, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read assistant file %s: %w", path, err)
+	}
+
+	draft := strings.TrimSpace(string(data))
+	if draft == "" {
+		return "", fmt.Errorf("assistant file %s is empty", path)
+	}
+	return draft, nil
+}
+
+// StdinIsPiped reports whether stdin comes from a pipe or file rather than a terminal
+func StdinIsPiped() bool {
+	return !term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// ReadPipedInput reads piped stdin, capped like --stdin-context
+func ReadPipedInput() (string, error) {
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", fmt.Errorf("failed to read stdin: %w", err)
+	}
+	return CapStdinContext(string(data)), nil
+}
+
+// CombineQueryWithInput makes piped input the data for the query's instruction,
+// or the query itself when no instruction was given
+func CombineQueryWithInput(query string, input string) string {
+	input = strings.TrimRight(input, "\n")
+	if strings.TrimSpace(input) == "" {
+		return query
+	}
+	if strings.TrimSpace(query) == "" {
+		return input
+	}
+	return query + "\n\n```\n" + input + "\n```"
+}
+
+// maxStdinContextBytes caps how much piped input --stdin-context sends
+const maxStdinContextBytes = 64 * 1024
+
+// ReadStdinContext reads piped input for --stdin-context, keeping the end of
+// the input when it's larger than the cap since logs put recent lines last
+func ReadStdinContext() (string, error) {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect stdin: %w", err)
+	}
+	if info.Mode()&os.ModeCharDevice != 0 {
+		return "", fmt.Errorf("--stdin-context needs piped input, e.g. cat app.log | ask --stdin-context \"find the root cause\"")
+	}
+
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", fmt.Errorf("failed to read stdin: %w", err)
+	}
+	return CapStdinContext(string(data)), nil
+}
+
+// CapStdinContext trims piped input to maxStdinContextBytes, keeping the end
+func CapStdinContext(data string) string {
+	if len(data) > maxStdinContextBytes {
+		return "... (truncated)\n" + data[len(data)-maxStdinContextBytes:]
+	}
+	return data
+}
@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// commandSeparator splits a command line into the commands of a pipeline or list
+var commandSeparator = regexp.MustCompile(`\|\|?|&&|;`)
+
+// IsInteractiveCommand reports whether any program in a command line is in the
+// interactive list, e.g. "ps aux | less" or "sudo vim /etc/hosts"
+func IsInteractiveCommand(command string, interactive []string) bool {
+	for _, segment := range commandSeparator.Split(command, -1) {
+		program := programName(segment)
+		if program == "" {
+			continue
+		}
+		for _, name := range interactive {
+			if program == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// programName returns the program a command runs, skipping sudo, env and
+// leading VAR=value assignments
+func programName(command string) string {
+	for _, field := range strings.Fields(command) {
+		if field == "sudo" || field == "env" || field == "exec" || strings.Contains(field, "=") {
+			continue
+		}
+		if strings.HasPrefix(field, "-") {
+			continue
+		}
+		return filepath.Base(field)
+	}
+	return ""
+}
@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	// lockTimeout is how long rotation waits for another process holding the lock
+	lockTimeout = 2 * time.Second
+	// staleLockAge is when a lock left behind by a crashed process is broken
+	staleLockAge = 30 * time.Second
+)
+
+// rotate renames path to path.1 (shifting older backups up and dropping the oldest)
+// once it reaches MaxSize. Failures are ignored, logging must never stop the program.
+func (l *Logger) rotate(path string) {
+	if l.MaxSize <= 0 {
+		return
+	}
+	if info, err := os.Stat(path); err != nil || info.Size() < l.MaxSize {
+		return
+	}
+
+	unlock, err := lockFile(path)
+	if err != nil {
+		return
+	}
+	defer unlock()
+
+	// Another process may have rotated the file while we waited for the lock
+	if info, err := os.Stat(path); err != nil || info.Size() < l.MaxSize {
+		return
+	}
+
+	if l.MaxBackups <= 0 {
+		os.Remove(path)
+		return
+	}
+	os.Remove(backupPath(path, l.MaxBackups))
+	for i := l.MaxBackups - 1; i >= 1; i-- {
+		os.Rename(backupPath(path, i), backupPath(path, i+1))
+	}
+	os.Rename(path, backupPath(path, 1))
+}
+
+// backupPath returns the name of the nth rotated file
+func backupPath(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
+
+// lockFile takes an exclusive lock on path by creating path.lock, which works the
+// same on every platform. It returns a function releasing the lock.
+func lockFile(path string) (func(), error) {
+	lockPath := path + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		if info, statErr := os.Stat(lockPath); statErr == nil && time.Since(info.ModTime()) > staleLockAge {
+			os.Remove(lockPath)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for lock %s", lockPath)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
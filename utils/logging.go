@@ -20,19 +20,106 @@ type CommandHistoryItem struct {
 type Logger struct {
 	CommandHistoryPath string
 	ApplicationLogPath string
+	LastResponsePath   string
+	LastCommandPath    string
+	// HistoryEmbeddingsPath caches embeddings of history queries for --history-search
+	HistoryEmbeddingsPath string
+	// ResponseCachePath keeps answers served when offline_fallback finds the network down
+	ResponseCachePath string
+	// Enabled is false with --no-log; writes to the log and history files are then skipped
+	Enabled bool
+	// MaxSize is the size in bytes at which the log and history files are rotated
+	MaxSize int64
+	// MaxBackups is how many rotated files (.1, .2, ...) are kept
+	MaxBackups int
+}
+
+// Rotation defaults, changed with SetLogRotation
+var (
+	logMaxSize    int64 = 10 << 20
+	logMaxBackups       = 3
+)
+
+// SetLogRotation sets the rotation size and kept backups for loggers created after it
+func SetLogRotation(maxSize int64, maxBackups int) {
+	logMaxSize, logMaxBackups = maxSize, maxBackups
+}
+
+// loggingEnabled is the default for new loggers, turned off by --no-log and private mode
+var loggingEnabled = true
+
+// SetLoggingEnabled turns writing logs and history on or off for loggers created after it
+func SetLoggingEnabled(enabled bool) {
+	loggingEnabled = enabled
 }
 
 // NewLogger creates a new logger instance
 func NewLogger() *Logger {
-	tempDir := os.TempDir()
+	dir := DataDir()
 	return &Logger{
-		CommandHistoryPath: filepath.Join(tempDir, "askta_Chistory.log"),
-		ApplicationLogPath: filepath.Join(tempDir, "askta_run.log"),
+		Enabled:    loggingEnabled,
+		MaxSize:    logMaxSize,
+		MaxBackups: logMaxBackups,
+
+		CommandHistoryPath: filepath.Join(dir, "askta_Chistory.log"),
+		ApplicationLogPath: filepath.Join(dir, "askta_run.log"),
+		LastResponsePath:   filepath.Join(dir, "askta_last_response.txt"),
+		LastCommandPath:    filepath.Join(dir, "askta_last_command.txt"),
+
+		HistoryEmbeddingsPath: filepath.Join(dir, "askta_history_embeddings.json"),
+		ResponseCachePath:     filepath.Join(dir, "askta_response_cache.json"),
+	}
+}
+
+// SaveLastResponse stores the most recent AI response for --copy-last
+func (l *Logger) SaveLastResponse(response string) error {
+	if !l.Enabled {
+		return nil
+	}
+	if err := os.WriteFile(l.LastResponsePath, []byte(response), 0600); err != nil {
+		return fmt.Errorf("failed to save last response: %w", err)
+	}
+	return nil
+}
+
+// SaveLastCommand stores the most recently executed command for --copy-last-command
+func (l *Logger) SaveLastCommand(command string) error {
+	if !l.Enabled {
+		return nil
+	}
+	if err := os.WriteFile(l.LastCommandPath, []byte(command), 0600); err != nil {
+		return fmt.Errorf("failed to save last command: %w", err)
+	}
+	return nil
+}
+
+// ReadLastResponse returns the most recent AI response
+func (l *Logger) ReadLastResponse() (string, error) {
+	return readLastFile(l.LastResponsePath, "response")
+}
+
+// ReadLastCommand returns the most recently executed command
+func (l *Logger) ReadLastCommand() (string, error) {
+	return readLastFile(l.LastCommandPath, "command")
+}
+
+// readLastFile reads one of the "last ..." files, with a clear error when nothing was saved yet
+func readLastFile(path string, what string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("no previous %s found", what)
+		}
+		return "", fmt.Errorf("failed to read last %s: %w", what, err)
 	}
+	return string(data), nil
 }
 
 // LogCommand records a command suggestion to history
 func (l *Logger) LogCommand(query string, commands map[string]string) error {
+	if !l.Enabled {
+		return nil
+	}
 	// Create history item
 	item := CommandHistoryItem{
 		Timestamp: time.Now().Format(time.RFC3339),
@@ -47,6 +134,7 @@ func (l *Logger) LogCommand(query string, commands map[string]string) error {
 	}
 
 	// Append to file
+	l.rotate(l.CommandHistoryPath)
 	f, err := os.OpenFile(l.CommandHistoryPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		return fmt.Errorf("failed to open history file: %w", err)
@@ -62,8 +150,12 @@ func (l *Logger) LogCommand(query string, commands map[string]string) error {
 
 // LogApplication logs application events
 func (l *Logger) LogApplication(message string) error {
+	if !l.Enabled {
+		return nil
+	}
 	logEntry := fmt.Sprintf("[%s] %s\n", time.Now().Format(time.RFC3339), message)
 
+	l.rotate(l.ApplicationLogPath)
 	f, err := os.OpenFile(l.ApplicationLogPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		return fmt.Errorf("failed to open log file: %w", err)
@@ -77,10 +169,13 @@ func (l *Logger) LogApplication(message string) error {
 	return nil
 }
 
+// DefaultHistoryLimit is the number of history entries shown when no limit is configured
+const DefaultHistoryLimit = 1000
+
 // GetRecentCommands retrieves the most recent command history entries
 func (l *Logger) GetRecentCommands(limit int) ([]CommandHistoryItem, error) {
 	if limit <= 0 {
-		limit = 1000 // Default to 1000 entries if no limit is provided
+		limit = DefaultHistoryLimit // Default to 1000 entries if no limit is provided
 	}
 
 	// Read the history file
@@ -113,6 +208,19 @@ func (l *Logger) GetRecentCommands(limit int) ([]CommandHistoryItem, error) {
 	return items, nil
 }
 
+// TailApplicationLog returns the last n lines of the application log
+func (l *Logger) TailApplicationLog(n int) ([]string, error) {
+	data, err := os.ReadFile(l.ApplicationLogPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read application log: %w", err)
+	}
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines, nil
+}
+
 // LogInfo logs an informational message
 func LogInfo(message string) {
 	logger := NewLogger()
@@ -134,6 +242,13 @@ func LogSystemResponse(responseLength int, success bool, response string) {
 	}
 	_ = logger.LogApplication(fmt.Sprintf("[SYSTEM RESPONSE] Status: %s, Response length: %d chars", status, responseLength))
 
+	// Remember successful responses for --copy-last
+	if success {
+		if err := logger.SaveLastResponse(response); err != nil {
+			_ = logger.LogApplication("[ERROR] " + err.Error())
+		}
+	}
+
 	// Log the actual response content (might want to truncate very long responses)
 	if len(response) > 5000 {
 		_ = logger.LogApplication(fmt.Sprintf("[RESPONSE CONTENT] %s...(truncated)", response[:5000]))
@@ -146,4 +261,9 @@ func LogSystemResponse(responseLength int, success bool, response string) {
 func LogCommandExecution(command string) {
 	logger := NewLogger()
 	_ = logger.LogApplication(fmt.Sprintf("[COMMAND EXECUTED] %s", command))
+
+	// Remember the command for --copy-last-command
+	if err := logger.SaveLastCommand(command); err != nil {
+		_ = logger.LogApplication("[ERROR] " + err.Error())
+	}
 }
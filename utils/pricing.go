@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"ask_terminal/dto"
+	"fmt"
+	"strings"
+)
+
+// ModelPrice is what a model costs in USD per million tokens
+type ModelPrice struct {
+	Input  float64
+	Output float64
+}
+
+// modelPrices lists list prices by model name prefix; the longest matching prefix
+// wins, so "gpt-4o-mini" isn't priced as "gpt-4o". Local and unknown models have no price.
+var modelPrices = map[string]ModelPrice{
+	"gpt-4o":            {Input: 2.50, Output: 10.00},
+	"gpt-4o-mini":       {Input: 0.15, Output: 0.60},
+	"gpt-4.1":           {Input: 2.00, Output: 8.00},
+	"gpt-4.1-mini":      {Input: 0.40, Output: 1.60},
+	"gpt-4.1-nano":      {Input: 0.10, Output: 0.40},
+	"gpt-4-turbo":       {Input: 10.00, Output: 30.00},
+	"gpt-3.5-turbo":     {Input: 0.50, Output: 1.50},
+	"o1":                {Input: 15.00, Output: 60.00},
+	"o3":                {Input: 2.00, Output: 8.00},
+	"o3-mini":           {Input: 1.10, Output: 4.40},
+	"o4-mini":           {Input: 1.10, Output: 4.40},
+	"claude-3-5-haiku":  {Input: 0.80, Output: 4.00},
+	"claude-3-5-sonnet": {Input: 3.00, Output: 15.00},
+	"claude-3-7-sonnet": {Input: 3.00, Output: 15.00},
+	"claude-sonnet-4":   {Input: 3.00, Output: 15.00},
+	"claude-opus-4":     {Input: 15.00, Output: 75.00},
+	"deepseek-chat":     {Input: 0.27, Output: 1.10},
+	"deepseek-reasoner": {Input: 0.55, Output: 2.19},
+}
+
+// LookupPrice returns the price of a model, ignoring a "provider/" prefix as used by routers
+func LookupPrice(model string) (ModelPrice, bool) {
+	model = strings.ToLower(model)
+	if slash := strings.LastIndex(model, "/"); slash != -1 {
+		model = model[slash+1:]
+	}
+
+	best := ""
+	for prefix := range modelPrices {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	if best == "" {
+		return ModelPrice{}, false
+	}
+	return modelPrices[best], true
+}
+
+// TokenUsage is the token count of one request, as reported by the provider or estimated
+type TokenUsage struct {
+	Model      string
+	Prompt     int
+	Completion int
+	Estimated  bool // Counted locally because the provider didn't report usage
+}
+
+// Total returns the prompt and completion tokens together
+func (u TokenUsage) Total() int {
+	return u.Prompt + u.Completion
+}
+
+// UsageFor returns the usage the provider reported, or an estimate from the request
+// messages and the answer when it reported none (common for streams)
+func UsageFor(model string, reported *dto.Usage, request *dto.GeneralOpenAIRequest, answer string) TokenUsage {
+	if reported != nil && reported.PromptTokens+reported.CompletionTokens > 0 {
+		return TokenUsage{Model: model, Prompt: reported.PromptTokens, Completion: reported.CompletionTokens}
+	}
+
+	prompt := 0
+	for _, message := range request.Messages {
+		prompt += EstimateTokens(message.StringContent())
+	}
+	return TokenUsage{Model: model, Prompt: prompt, Completion: EstimateTokens(answer), Estimated: true}
+}
+
+// EstimateTokens approximates the token count of text at about four bytes per token,
+// the rule of thumb for English with BPE tokenizers
+func EstimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// Summary describes the usage and its estimated cost in one line
+func (u TokenUsage) Summary() string {
+	approx := ""
+	if u.Estimated {
+		approx = "~"
+	}
+	summary := fmt.Sprintf("Tokens: %s%d prompt + %s%d completion = %s%d total",
+		approx, u.Prompt, approx, u.Completion, approx, u.Total())
+
+	if price, ok := LookupPrice(u.Model); ok {
+		cost := (float64(u.Prompt)*price.Input + float64(u.Completion)*price.Output) / 1e6
+		summary += fmt.Sprintf(" · ~$%.4f", cost)
+	}
+	if u.Model != "" {
+		summary += " (" + u.Model + ")"
+	}
+	return summary
+}
@@ -3,9 +3,24 @@ package utils
 import (
 	"ask_terminal/config"
 	"ask_terminal/dto"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"os"
+	"strings"
+	"text/template"
 )
 
+// RequestContext returns the context for sending a BuildPrompt request, bounded by
+// request_timeout unless it is 0
+func RequestContext(conf *config.Config) (context.Context, context.CancelFunc) {
+	if timeout := conf.RequestTimeout(); timeout > 0 {
+		return context.WithTimeout(context.Background(), timeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
 // BuildPrompt constructs a suitable prompt based on the mode
 func BuildPrompt(userQuery string, conf *config.Config, mode string) *dto.GeneralOpenAIRequest {
 	// Build system context based on environment and configuration
@@ -16,56 +31,173 @@ func BuildPrompt(userQuery string, conf *config.Config, mode string) *dto.Genera
 	systemMessage.Role = "system"
 	systemMessage.SetStringContent(systemPrompt)
 
+	// Wrap the query in the configured template and add the trailing instruction
+	userQuery = ApplyQueryTemplate(userQuery, conf)
+	userQuery = AppendInstruction(userQuery, conf)
+
 	// Create user message
 	userMessage := dto.Message{}
 	userMessage.Role = "user"
 
 	if mode == "terminal" {
-		userMessage.SetStringContent("User request: " + userQuery)
+		userQuery = "User request: " + userQuery
+	}
+	if len(conf.Images) > 0 {
+		// Images go in as image_url parts after the text
+		parts := []dto.MediaContent{{Type: dto.ContentTypeText, Text: userQuery}}
+		for _, url := range conf.Images {
+			parts = append(parts, dto.MediaContent{Type: dto.ContentTypeImageURL, ImageUrl: &dto.MessageImageUrl{Url: url}})
+		}
+		userMessage.SetMediaContent(parts)
 	} else {
 		userMessage.SetStringContent(userQuery)
 	}
 
 	// Set up parameters based on mode
-	var temperature float64
+	var temperature *float64
 	var maxTokens uint
 
 	if mode == "terminal" {
 		// For terminal mode, always use these specific values
-		temperature = 0.0 // Lower temperature for more deterministic command suggestions
-		maxTokens = 500   // Limit token usage for command suggestions
+		zero := 0.0 // Lower temperature for more deterministic command suggestions
+		temperature = &zero
+		maxTokens = 500 // Limit token usage for command suggestions
 	} else {
-		// For conversation mode, use config values
-		temperature = conf.Temperature
+		// For conversation mode, use config values; model_defaults are already
+		// merged into conf.Temperature, so nil means neither set one
+		configured := conf.TemperatureValue()
+		temperature = &configured
 		maxTokens = conf.MaxTokens
 	}
 
-	// Create JSON response format for terminal mode
+	// Create JSON response format for terminal mode, or the configured format for other modes
 	var responseFormat *dto.ResponseFormat
 	if mode == "terminal" {
+		if !conf.NoJSON {
+			responseFormat = &dto.ResponseFormat{
+				Type: "json_object",
+			}
+		}
+	} else if conf.ResponseFormat != "" {
 		responseFormat = &dto.ResponseFormat{
-			Type: "json_object",
+			Type: conf.ResponseFormat,
 		}
 	}
 
+	// An earlier answer goes in as the assistant's previous turn, so the query
+	// asks for a revision of it rather than a fresh answer
+	messages := []dto.Message{systemMessage, userMessage}
+	if conf.AssistantDraft != "" && mode != "terminal" {
+		draftMessage := dto.Message{Role: "assistant"}
+		draftMessage.SetStringContent(conf.AssistantDraft)
+		messages = []dto.Message{systemMessage, draftMessage, userMessage}
+	}
+
 	// Build the request
 	request := &dto.GeneralOpenAIRequest{
-		Model:          conf.ModelName,
-		Messages:       []dto.Message{systemMessage, userMessage},
-		Temperature:    &temperature,
+		Model:          conf.ModelFor(mode),
+		Messages:       messages,
+		Temperature:    temperature,
 		MaxTokens:      maxTokens,
 		ResponseFormat: responseFormat,
 	}
 
+	// Reasoning models accept an effort level, configured per model in model_defaults
+	if mode != "terminal" {
+		request.ReasoningEffort = conf.ReasoningEffort
+	}
+
+	// Route requests with the same system context to the same prefix cache
+	if conf.PromptCache {
+		request.PromptCacheKey = promptCacheKey(systemPrompt)
+	}
+
 	return request
 }
 
-// buildSystemContext creates a system prompt with environment information
+// promptCacheKey derives a stable cache key from the system context
+func promptCacheKey(systemPrompt string) string {
+	sum := sha256.Sum256([]byte(systemPrompt))
+	return "askta-" + hex.EncodeToString(sum[:8])
+}
+
+// AppendInstruction adds the configured trailing instruction to a query
+func AppendInstruction(query string, conf *config.Config) string {
+	if conf.AppendInstruction == "" {
+		return query
+	}
+	return query + "\n\n" + conf.AppendInstruction
+}
+
+// queryTemplateData is the data available to query_template
+type queryTemplateData struct {
+	Query string
+}
+
+// ParseQueryTemplate parses the configured query_template, nil when none is set
+func ParseQueryTemplate(conf *config.Config) (*template.Template, error) {
+	if conf.QueryTemplate == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New("query_template").Option("missingkey=error").Parse(conf.QueryTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query_template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// ApplyQueryTemplate substitutes a query into the configured query_template,
+// returning the query unchanged when no template is set or it fails to render
+func ApplyQueryTemplate(query string, conf *config.Config) string {
+	tmpl, err := ParseQueryTemplate(conf)
+	if err != nil {
+		LogError("Error applying query template", err)
+		return query
+	}
+	if tmpl == nil {
+		return query
+	}
+
+	var result strings.Builder
+	if err := tmpl.Execute(&result, queryTemplateData{Query: query}); err != nil {
+		LogError("Error applying query template", err)
+		return query
+	}
+	return result.String()
+}
+
+// DefaultScriptPrompt is the system prompt used by script mode unless script_prompt is set
+const DefaultScriptPrompt = `You are a shell scripting expert. Write a complete, well commented shell script for the user's task.
+Start the script with a shebang line, fail early on errors, and reply with the script in a single fenced code block.`
+
+// DefaultAgentPrompt is the system prompt of --agent mode
+const DefaultAgentPrompt = `You are a command line expert investigating the user's question on their machine.
+Use the run_shell_command tool to run commands that gather what you need, one step at a time, preferring read-only commands.
+The user confirms every command and may decline one. When you know enough, answer concisely in markdown without calling the tool.`
+
+// SystemPrompt returns the system message BuildPrompt sends for mode
+func SystemPrompt(conf *config.Config, mode string) string {
+	return buildSystemContext(conf, mode)
+}
+
+// buildSystemContext creates a system prompt with environment information, from
+// the mode's entry under templates: when there is one
 func buildSystemContext(conf *config.Config, mode string) string {
+	if systemPrompt, ok := templateSystemPrompt(conf, mode); ok {
+		return systemPrompt + requestContext(conf)
+	}
+
 	var systemPrompt string
 
 	if mode == "terminal" {
 		systemPrompt = `You are a command line expert. Help the user with their terminal commands.`
+	} else if mode == "agent" {
+		systemPrompt = DefaultAgentPrompt
+	} else if mode == "script" {
+		systemPrompt = DefaultScriptPrompt
+		if conf.ScriptPrompt != "" {
+			systemPrompt = conf.ScriptPrompt
+		}
 	} else {
 		systemPrompt = `You are a helpful assist.`
 	}
@@ -73,25 +205,87 @@ func buildSystemContext(conf *config.Config, mode string) string {
 	// Add OS info
 	systemPrompt += "\nCurrent environment:\n- Operating system: " + GetSystemInfo()
 
-	// Add current directory if not in private mode
-	if !conf.PrivateMode {
-		cwd, err := os.Getwd()
-		if err == nil {
-			systemPrompt += "\n- Working directory: " + cwd
+	// Commands run in the user's shell, so write them in its syntax
+	if mode == "terminal" || mode == "agent" {
+		systemPrompt += "\n- Shell: " + Shell() + " (write every command in its syntax)"
+	}
 
-			// Add directory structure
-			systemPrompt += "\nDirectory structure:\n" + GetDirectoryStructure(1)
+	// Add current directory if not in private mode
+	if cwd, tree := directoryContext(conf); cwd != "" {
+		systemPrompt += "\n- Working directory: " + cwd
+		if tree != "" {
+			systemPrompt += "\nDirectory structure:\n" + tree
 		}
 	}
 
+	systemPrompt += requestContext(conf)
+
 	// Add user's system prompt if any
 	if conf.SysPrompt != "" {
 		systemPrompt += "\nUser's system prompt: " + conf.SysPrompt
 	}
 
-	// Add formatting instructions for terminal mode
-	if mode == "terminal" {
-		systemPrompt += `
+	return systemPrompt + formatInstructions(conf, mode)
+}
+
+// directoryContext returns the working directory and its structure, both empty in
+// private mode. The structure is left out with images, which use plenty of tokens already.
+func directoryContext(conf *config.Config) (cwd string, tree string) {
+	if conf.PrivateMode {
+		return "", ""
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", ""
+	}
+	if len(conf.Images) == 0 {
+		tree = GetDirectoryStructure(1, conf.ContextExtensions, conf.ContextIgnoreList(), conf.DirWalkWorkerCount())
+	}
+	return cwd, tree
+}
+
+// requestContext returns the context the user attached to this request: included
+// files, recent shell commands and piped input
+func requestContext(conf *config.Config) string {
+	var systemPrompt string
+
+	// Add contents of files the user explicitly included
+	if len(conf.Include) > 0 {
+		included, err := BuildIncludedFiles(conf.Include, conf.ContextExtensions, conf.ContextIgnoreList())
+		if err != nil {
+			LogError("Failed to include files", err)
+		} else if included.Content != "" {
+			systemPrompt += "\nIncluded files:" + included.Content
+		}
+	}
+
+	// Add what the user just ran so suggestions can build on it; private mode keeps it local
+	if conf.RecentCommands > 0 && !conf.PrivateMode {
+		if commands := RecentCommands(conf.RecentCommands); len(commands) > 0 {
+			systemPrompt += "\nRecent shell commands (oldest first):\n- " + strings.Join(commands, "\n- ")
+		}
+	}
+
+	// Add piped input as background context; private mode keeps it local
+	if conf.StdinContext != "" && !conf.PrivateMode {
+		systemPrompt += "\nContext from the user's piped input:\n<<<STDIN\n" +
+			strings.TrimRight(conf.StdinContext, "\n") + "\nSTDIN>>>"
+	}
+
+	return systemPrompt
+}
+
+// formatInstructions returns the answer format the mode expects, empty when it
+// has none
+func formatInstructions(conf *config.Config, mode string) string {
+	if mode == "terminal" && conf.NoJSON {
+		return `
+Respond with one command suggestion per line, formatted as follows, without any other text:
+ls -la - ls is a command to view files or folders in the current directory. -l shows details and -a shows hidden files.
+command - description
+`
+	} else if mode == "terminal" {
+		return `
 Strictly respond with a JSON array of command suggestions formatted as follows:
 [
   {"1": {"ls -la": "ls is a command to view files or folders in the current directory. -l is a parameter to display more detailed information, and -a is to show hidden files."}},
@@ -100,8 +294,33 @@ Strictly respond with a JSON array of command suggestions formatted as follows:
 ]
 `
 	}
+	return ""
+}
 
-	return systemPrompt
+// templateSystemPrompt renders the mode's entry under templates:, reporting false
+// when there is none or it fails so the built-in prompt is used
+func templateSystemPrompt(conf *config.Config, mode string) (string, bool) {
+	tmpl, err := conf.PromptTemplate(mode)
+	if err != nil || tmpl == nil {
+		return "", false
+	}
+
+	cwd, tree := directoryContext(conf)
+	data := config.PromptTemplateData{
+		OS:            GetSystemInfo(),
+		Shell:         Shell(),
+		CWD:           cwd,
+		DirTree:       tree,
+		UserSysPrompt: conf.SysPrompt,
+		Format:        formatInstructions(conf, mode),
+	}
+
+	var result strings.Builder
+	if err := tmpl.Execute(&result, data); err != nil {
+		LogError("Error applying the "+mode+" template, using the built-in prompt", err)
+		return "", false
+	}
+	return result.String(), true
 }
 
 // GetSystemInfo returns information about the current system
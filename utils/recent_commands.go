@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RecentCommandsFile is where the shell hook documented in the README appends each
+// command as it runs; ASKTA_RECENT_COMMANDS overrides the location
+const RecentCommandsFile = ".askta_recent_commands"
+
+// RecentCommands returns up to n of the user's most recent shell commands, oldest
+// first. It prefers the hook file, which is current, and falls back to the shell
+// history file, which bash only writes when a shell exits.
+func RecentCommands(n int) []string {
+	if n <= 0 {
+		return nil
+	}
+	for _, path := range recentCommandSources() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if commands := lastCommands(string(data), n); len(commands) > 0 {
+			return commands
+		}
+	}
+	return nil
+}
+
+// recentCommandSources lists the files to read commands from, in order of preference
+func recentCommandSources() []string {
+	var sources []string
+	if path := os.Getenv("ASKTA_RECENT_COMMANDS"); path != "" {
+		sources = append(sources, path)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return sources
+	}
+	sources = append(sources, filepath.Join(home, RecentCommandsFile))
+	if path := os.Getenv("HISTFILE"); path != "" {
+		sources = append(sources, path)
+	}
+	return append(sources, filepath.Join(home, ".bash_history"), filepath.Join(home, ".zsh_history"))
+}
+
+// lastCommands returns the last n commands of a history file, skipping calls to ask itself
+func lastCommands(history string, n int) []string {
+	lines := strings.Split(history, "\n")
+	var commands []string
+	for i := len(lines) - 1; i >= 0 && len(commands) < n; i-- {
+		command := strings.TrimSpace(lines[i])
+		// zsh extended history lines look like ": 1700000000:0;git status"
+		if strings.HasPrefix(command, ": ") {
+			if _, rest, ok := strings.Cut(command, ";"); ok {
+				command = strings.TrimSpace(rest)
+			}
+		}
+		if command == "" || strings.HasPrefix(command, "#") || command == "ask" || strings.HasPrefix(command, "ask ") {
+			continue
+		}
+		commands = append(commands, command)
+	}
+
+	// Oldest first, the order they ran in
+	for i, j := 0, len(commands)-1; i < j; i, j = i+1, j-1 {
+		commands[i], commands[j] = commands[j], commands[i]
+	}
+	return commands
+}
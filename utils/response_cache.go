@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"sort"
+	"time"
+)
+
+// maxCachedResponses caps the offline fallback cache, dropping the oldest entries
+const maxCachedResponses = 200
+
+// CachedResponse is an answer kept for offline_fallback
+type CachedResponse struct {
+	Content string    `json:"content"`
+	Saved   time.Time `json:"saved"`
+}
+
+// ResponseCacheKey identifies a query sent in a mode to a model
+func ResponseCacheKey(model, mode, query string) string {
+	sum := sha256.Sum256([]byte(model + "\x00" + mode + "\x00" + query))
+	return hex.EncodeToString(sum[:])
+}
+
+// SaveCachedResponse stores an answer under key; nothing is written when logging is off
+func (l *Logger) SaveCachedResponse(key string, content string) error {
+	if !l.Enabled {
+		return nil
+	}
+	entries := l.loadResponseCache()
+	entries[key] = CachedResponse{Content: content, Saved: time.Now()}
+
+	if len(entries) > maxCachedResponses {
+		keys := make([]string, 0, len(entries))
+		for k := range entries {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return entries[keys[i]].Saved.Before(entries[keys[j]].Saved)
+		})
+		for _, k := range keys[:len(entries)-maxCachedResponses] {
+			delete(entries, k)
+		}
+	}
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(l.ResponseCachePath, data, 0600)
+}
+
+// LoadCachedResponse returns the answer stored under key, if any
+func (l *Logger) LoadCachedResponse(key string) (CachedResponse, bool) {
+	entry, ok := l.loadResponseCache()[key]
+	return entry, ok
+}
+
+// loadResponseCache reads the cache file, starting over when it's missing or unreadable
+func (l *Logger) loadResponseCache() map[string]CachedResponse {
+	entries := make(map[string]CachedResponse)
+	if data, err := os.ReadFile(l.ResponseCachePath); err == nil {
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return make(map[string]CachedResponse)
+		}
+	}
+	return entries
+}
@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// knownShells are the shell names DetectShell can report
+var knownShells = map[string]bool{
+	"bash": true, "zsh": true, "fish": true, "sh": true, "dash": true, "ksh": true,
+	"powershell": true, "pwsh": true, "cmd": true,
+}
+
+var (
+	// shellOverride is the shell config setting, set with SetShell
+	shellOverride string
+
+	detectShellOnce sync.Once
+	detectedShell   string
+)
+
+// SetShell makes Shell return name instead of the detected shell; an empty name
+// restores detection
+func SetShell(name string) {
+	shellOverride = strings.ToLower(strings.TrimSpace(name))
+}
+
+// Shell returns the shell suggestions are written for and commands run in: the shell
+// config setting, or the detected shell
+func Shell() string {
+	if shellOverride != "" {
+		return shellOverride
+	}
+	detectShellOnce.Do(func() {
+		detectedShell = DetectShell()
+	})
+	return detectedShell
+}
+
+// DetectShell guesses the user's shell from the parent process, then $PSModulePath,
+// ComSpec and $SHELL, falling back to bash (cmd on Windows)
+func DetectShell() string {
+	// ask is usually started by the interactive shell, which can differ from the login shell in $SHELL
+	if name := shellName(parentProcessName()); name != "" {
+		return name
+	}
+
+	if runtime.GOOS == "windows" {
+		// PowerShell adds the user's module directory to PSModulePath, cmd only inherits the system one
+		if profile := os.Getenv("USERPROFILE"); profile != "" &&
+			strings.Contains(strings.ToLower(os.Getenv("PSModulePath")), strings.ToLower(profile)) {
+			return "powershell"
+		}
+		if name := shellName(os.Getenv("ComSpec")); name != "" {
+			return name
+		}
+		return "cmd"
+	}
+
+	if name := shellName(os.Getenv("SHELL")); name != "" {
+		return name
+	}
+	return "bash"
+}
+
+// shellName returns the known shell a program path or process name refers to, or ""
+func shellName(program string) string {
+	name := strings.ToLower(filepath.Base(strings.TrimSpace(program)))
+	name = strings.TrimSuffix(name, ".exe")
+	// Login shells show up as "-bash"
+	name = strings.TrimPrefix(name, "-")
+	if knownShells[name] {
+		return name
+	}
+	return ""
+}
+
+// parentProcessName returns the name of the process that started ask, or "" when
+// it can't be found
+func parentProcessName() string {
+	ppid := os.Getppid()
+	if ppid <= 1 {
+		return ""
+	}
+	switch runtime.GOOS {
+	case "linux":
+		data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(ppid), "comm"))
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(data))
+	case "windows":
+		return ""
+	default:
+		out, err := exec.Command("ps", "-o", "comm=", "-p", strconv.Itoa(ppid)).Output()
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(out))
+	}
+}
+
+// ShellCommand wraps a command line in shell, falling back to the platform default
+// when that shell isn't installed
+func ShellCommand(shell string, command string) *exec.Cmd {
+	switch shell {
+	case "powershell", "pwsh":
+		if _, err := exec.LookPath(shell); err == nil {
+			return exec.Command(shell, "-NoProfile", "-Command", command)
+		}
+	case "cmd":
+		if runtime.GOOS == "windows" {
+			return exec.Command("cmd", "/C", command)
+		}
+	default:
+		if _, err := exec.LookPath(shell); err == nil {
+			return exec.Command(shell, "-c", command)
+		}
+	}
+
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", command)
+	}
+	return exec.Command("bash", "-c", command)
+}
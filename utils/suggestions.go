@@ -0,0 +1,369 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Suggestion is a command suggested by the AI, shared by every mode
+type Suggestion struct {
+	Command     string `json:"command"`
+	Description string `json:"description"`
+}
+
+// ParseSuggestions extracts command suggestions from a model answer. It accepts
+// the nested JSON format from the terminal prompt ([{"1": {"cmd": "desc"}}]),
+// flat JSON ([{"command": "...", "description": "..."}] or {"cmd": "desc"}),
+// either of those wrapped in a fenced code block, and plain "cmd - desc" lines.
+// Empty, duplicate and prose "commands" are dropped, see FilterSuggestions.
+func ParseSuggestions(content string) ([]Suggestion, error) {
+	// Windows line endings would leave "\r" in plain-text commands
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	body := stripCodeFence(strings.TrimSpace(content))
+
+	value, jsonErr := decodeOrderedJSON([]byte(body))
+	if jsonErr == nil {
+		if suggestions := filterAndLog(collectSuggestions(value)); len(suggestions) > 0 {
+			return suggestions, nil
+		}
+		jsonErr = fmt.Errorf("no commands found in JSON answer")
+	}
+
+	// Try to extract commands using a fallback approach
+	if suggestions := filterAndLog(ExtractSuggestionsFromText(content)); len(suggestions) > 0 {
+		return suggestions, nil
+	}
+
+	return nil, fmt.Errorf("failed to parse suggestions: %w", jsonErr)
+}
+
+// FilterSuggestions drops suggestions with an empty command, repeats of an earlier
+// command, and "commands" that are really sentences. It returns the kept
+// suggestions and how many were dropped.
+func FilterSuggestions(suggestions []Suggestion) ([]Suggestion, int) {
+	seen := make(map[string]bool)
+	kept := make([]Suggestion, 0, len(suggestions))
+	for _, sugg := range suggestions {
+		if keepSuggestion(sugg, seen) {
+			kept = append(kept, sugg)
+		}
+	}
+	return kept, len(suggestions) - len(kept)
+}
+
+// filterAndLog filters suggestions and logs how many were dropped
+func filterAndLog(suggestions []Suggestion) []Suggestion {
+	kept, dropped := FilterSuggestions(suggestions)
+	if dropped > 0 {
+		LogInfo(fmt.Sprintf("Filtered out %d of %d suggestions (empty, duplicate or not a command)", dropped, len(suggestions)))
+	}
+	return kept
+}
+
+// keepSuggestion reports whether a suggestion is a usable command not in seen, and
+// adds it to seen
+func keepSuggestion(sugg Suggestion, seen map[string]bool) bool {
+	command := strings.Join(strings.Fields(sugg.Command), " ")
+	if command == "" || seen[command] || looksLikeProse(command) {
+		return false
+	}
+	seen[command] = true
+	return true
+}
+
+// looksLikeProse reports whether a command is a sentence rather than something to
+// run: several words without shell syntax, starting with a capital letter and
+// ending in sentence punctuation. Only the text is looked at, so the result doesn't
+// depend on what is installed.
+func looksLikeProse(command string) bool {
+	words := strings.Fields(command)
+	if len(words) < 3 || strings.ContainsAny(command, "|&;<>$`=/\\-*\"'") {
+		return false
+	}
+	capitalised := words[0][0] >= 'A' && words[0][0] <= 'Z'
+	return capitalised && strings.ContainsAny(command[len(command)-1:], ".?!:")
+}
+
+// stripCodeFence returns the contents of a fenced code block, or content unchanged
+func stripCodeFence(content string) string {
+	start := strings.Index(content, "```")
+	if start == -1 {
+		return content
+	}
+
+	// Skip the language tag on the opening fence
+	body := content[start+3:]
+	if newline := strings.Index(body, "\n"); newline != -1 {
+		body = body[newline+1:]
+	}
+	if end := strings.Index(body, "```"); end != -1 {
+		body = body[:end]
+	}
+	return strings.TrimSpace(body)
+}
+
+// orderedObject is a JSON object that remembers the order of its keys
+type orderedObject struct {
+	keys   []string
+	values map[string]any
+}
+
+// decodeOrderedJSON decodes JSON keeping object key order, so suggestions stay in the model's order
+func decodeOrderedJSON(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	value, err := decodeOrderedValue(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after JSON value")
+	}
+	return value, nil
+}
+
+// decodeOrderedValue reads one JSON value from the decoder
+func decodeOrderedValue(dec *json.Decoder) (any, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token {
+	case json.Delim('{'):
+		object := orderedObject{values: make(map[string]any)}
+		for dec.More() {
+			keyToken, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, _ := keyToken.(string)
+			value, err := decodeOrderedValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			if _, exists := object.values[key]; !exists {
+				object.keys = append(object.keys, key)
+			}
+			object.values[key] = value
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return object, nil
+
+	case json.Delim('['):
+		var items []any
+		for dec.More() {
+			item, err := decodeOrderedValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return items, nil
+	}
+
+	return token, nil
+}
+
+// collectSuggestions walks a decoded JSON value and gathers the suggestions in it
+func collectSuggestions(value any) []Suggestion {
+	var suggestions []Suggestion
+
+	switch v := value.(type) {
+	case []any:
+		for _, item := range v {
+			suggestions = append(suggestions, collectSuggestions(item)...)
+		}
+
+	case orderedObject:
+		// Flat {"command": "...", "description": "..."} item
+		if command, ok := v.values["command"].(string); ok {
+			return []Suggestion{{
+				Command:     strings.TrimSpace(command),
+				Description: firstString(v.values, "description", "desc", "explanation"),
+			}}
+		}
+
+		for _, key := range sortedKeys(v.keys) {
+			switch item := v.values[key].(type) {
+			case string:
+				if isIndexKey(key) {
+					// {"1": "ls -la"} has no description
+					suggestions = append(suggestions, Suggestion{Command: strings.TrimSpace(item)})
+				} else {
+					// {"ls -la": "description"}
+					suggestions = append(suggestions, Suggestion{Command: strings.TrimSpace(key), Description: item})
+				}
+			default:
+				// {"1": {...}} or {"suggestions": [...]}
+				suggestions = append(suggestions, collectSuggestions(item)...)
+			}
+		}
+	}
+
+	return suggestions
+}
+
+// sortedKeys orders numbered keys ("1", "2", "10") numerically and keeps other keys in place
+func sortedKeys(keys []string) []string {
+	for _, key := range keys {
+		if !isIndexKey(key) {
+			return keys
+		}
+	}
+
+	sorted := make([]string, len(keys))
+	copy(sorted, keys)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, _ := strconv.Atoi(sorted[i])
+		b, _ := strconv.Atoi(sorted[j])
+		return a < b
+	})
+	return sorted
+}
+
+// isIndexKey reports whether a key is a suggestion number such as "1"
+func isIndexKey(key string) bool {
+	_, err := strconv.Atoi(key)
+	return err == nil
+}
+
+// firstString returns the first string value found under any of keys
+func firstString(values map[string]any, keys ...string) string {
+	for _, key := range keys {
+		if s, ok := values[key].(string); ok {
+			return s
+		}
+	}
+	return ""
+}
+
+// ExtractSuggestionsFromText extracts commands from non-JSON answers with
+// lines such as "command - description" or "command: description"
+func ExtractSuggestionsFromText(content string) []Suggestion {
+	var suggestions []Suggestion
+	lines := strings.Split(content, "\n")
+
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "```") {
+			continue
+		}
+
+		// Look for patterns like "command - description" or "command: description"
+		for _, separator := range []string{" - ", ": "} {
+			if parts := strings.SplitN(line, separator, 2); len(parts) == 2 {
+				cmd := strings.Trim(strings.TrimSpace(parts[0]), "`")
+				desc := strings.TrimSpace(parts[1])
+
+				// Skip if it doesn't look like a command
+				if len(cmd) > 0 && !strings.HasPrefix(cmd, "#") && !strings.HasPrefix(cmd, "//") {
+					suggestions = append(suggestions, Suggestion{
+						Command:     cmd,
+						Description: desc,
+					})
+					break
+				}
+			}
+		}
+	}
+
+	return suggestions
+}
+
+// SuggestionStream parses a streamed JSON suggestion answer incrementally. Each
+// innermost object ({"cmd": "desc"} or {"command": ..., "description": ...}) is
+// returned as soon as its closing brace arrives; the top-level value itself is
+// left to ParseSuggestions once the stream ends.
+type SuggestionStream struct {
+	buffer   strings.Builder
+	frames   []streamFrame // open objects and arrays, outermost first
+	inString bool          // inside a JSON string
+	escaped  bool          // previous character was a backslash inside a string
+	emitted  int           // number of suggestions returned so far
+	seen     map[string]bool
+}
+
+// streamFrame is an open JSON object or array
+type streamFrame struct {
+	start  int  // buffer offset of the opening bracket
+	object bool // true for objects, false for arrays
+	leaf   bool // object holds no nested objects or arrays
+}
+
+// Feed adds a streamed chunk and returns the suggestions completed by it
+func (s *SuggestionStream) Feed(chunk string) []Suggestion {
+	var completed []Suggestion
+
+	for i := 0; i < len(chunk); i++ {
+		c := chunk[i]
+		offset := s.buffer.Len()
+		s.buffer.WriteByte(c)
+
+		if s.inString {
+			switch {
+			case s.escaped:
+				s.escaped = false
+			case c == '\\':
+				s.escaped = true
+			case c == '"':
+				s.inString = false
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			s.inString = len(s.frames) > 0
+		case '{', '[':
+			if len(s.frames) > 0 {
+				s.frames[len(s.frames)-1].leaf = false
+			}
+			s.frames = append(s.frames, streamFrame{start: offset, object: c == '{', leaf: true})
+		case '}', ']':
+			if len(s.frames) == 0 {
+				continue
+			}
+			frame := s.frames[len(s.frames)-1]
+			s.frames = s.frames[:len(s.frames)-1]
+
+			// Nested leaf objects are complete suggestions
+			if c == '}' && frame.object && frame.leaf && len(s.frames) > 0 {
+				element := s.buffer.String()[frame.start : offset+1]
+				if value, err := decodeOrderedJSON([]byte(element)); err == nil {
+					if s.seen == nil {
+						s.seen = make(map[string]bool)
+					}
+					for _, sugg := range collectSuggestions(value) {
+						if keepSuggestion(sugg, s.seen) {
+							s.emitted++
+							completed = append(completed, sugg)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return completed
+}
+
+// Content returns everything fed so far
+func (s *SuggestionStream) Content() string {
+	return s.buffer.String()
+}
+
+// Emitted returns how many suggestions Feed has returned
+func (s *SuggestionStream) Emitted() int {
+	return s.emitted
+}
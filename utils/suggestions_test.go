@@ -0,0 +1,218 @@
+package utils
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// Keep the filter's log lines out of the real data dir
+	dir, err := os.MkdirTemp("", "askta_test")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("XDG_DATA_HOME", dir)
+	SetLoggingEnabled(false)
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func TestParseSuggestions(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []Suggestion
+	}{
+		{
+			name:    "nested JSON",
+			content: `[{"1": {"ls -la": "list all files"}}, {"2": {"du -sh .": "show disk usage"}}]`,
+			want: []Suggestion{
+				{Command: "ls -la", Description: "list all files"},
+				{Command: "du -sh .", Description: "show disk usage"},
+			},
+		},
+		{
+			name:    "nested JSON ordered by number",
+			content: `{"10": {"pwd": "print directory"}, "2": {"ls": "list files"}}`,
+			want: []Suggestion{
+				{Command: "ls", Description: "list files"},
+				{Command: "pwd", Description: "print directory"},
+			},
+		},
+		{
+			name:    "flat JSON",
+			content: `[{"command": "git status", "description": "show changes"}, {"command": "git log", "desc": "show history"}]`,
+			want: []Suggestion{
+				{Command: "git status", Description: "show changes"},
+				{Command: "git log", Description: "show history"},
+			},
+		},
+		{
+			name:    "flat JSON object",
+			content: `{"df -h": "free disk space"}`,
+			want:    []Suggestion{{Command: "df -h", Description: "free disk space"}},
+		},
+		{
+			name:    "fenced JSON block",
+			content: "Here you go:\n```json\n[{\"1\": {\"ps aux\": \"list processes\"}}]\n```\n",
+			want:    []Suggestion{{Command: "ps aux", Description: "list processes"}},
+		},
+		{
+			name:    "plain text fallback",
+			content: "ls -la - list all files\r\n`du -sh *`: size of each entry\n",
+			want: []Suggestion{
+				{Command: "ls -la", Description: "list all files"},
+				{Command: "du -sh *", Description: "size of each entry"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSuggestions(tt.content)
+			if err != nil {
+				t.Fatalf("ParseSuggestions() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSuggestions() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSuggestionsNoCommands(t *testing.T) {
+	for _, content := range []string{"", "[]", "{}"} {
+		if got, err := ParseSuggestions(content); err == nil {
+			t.Errorf("ParseSuggestions(%q) = %#v, want an error", content, got)
+		}
+	}
+}
+
+func TestFilterSuggestions(t *testing.T) {
+	tests := []struct {
+		name        string
+		suggestions []Suggestion
+		want        []Suggestion
+		dropped     int
+	}{
+		{
+			name:        "empty command",
+			suggestions: []Suggestion{{Command: "  "}, {Command: "ls"}},
+			want:        []Suggestion{{Command: "ls"}},
+			dropped:     1,
+		},
+		{
+			name:        "duplicate ignoring spacing",
+			suggestions: []Suggestion{{Command: "ls  -la", Description: "first"}, {Command: "ls -la", Description: "again"}},
+			want:        []Suggestion{{Command: "ls  -la", Description: "first"}},
+			dropped:     1,
+		},
+		{
+			name: "prose",
+			suggestions: []Suggestion{
+				{Command: "Run the following command."},
+				{Command: "Try these files instead!"},
+				{Command: "find . -name notes.txt"},
+			},
+			want:    []Suggestion{{Command: "find . -name notes.txt"}},
+			dropped: 2,
+		},
+		{
+			name:        "short commands are never prose",
+			suggestions: []Suggestion{{Command: "Done."}, {Command: "make test"}},
+			want:        []Suggestion{{Command: "Done."}, {Command: "make test"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, dropped := FilterSuggestions(tt.suggestions)
+			if !reflect.DeepEqual(got, tt.want) || dropped != tt.dropped {
+				t.Errorf("FilterSuggestions() = %#v, %d, want %#v, %d", got, dropped, tt.want, tt.dropped)
+			}
+		})
+	}
+}
+
+func TestLooksLikeProse(t *testing.T) {
+	tests := []struct {
+		command string
+		want    bool
+	}{
+		{"Here is the command:", true},
+		{"Compare these two files?", true},
+		{"zzqx these files now.", false},
+		{"Zzqx these files now", false},
+		{"tar -czf backup.tar.gz dir", false},
+		{"cat file | grep error", false},
+		{"echo hello world", false},
+		{"Please", false},
+	}
+
+	for _, tt := range tests {
+		if got := looksLikeProse(tt.command); got != tt.want {
+			t.Errorf("looksLikeProse(%q) = %v, want %v", tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestSuggestionStreamFeed(t *testing.T) {
+	answer := `[{"1": {"ls -la": "list files"}}, {"2": {"echo \"}{\"": "braces in a string"}}, {"3": {"ls -la": "repeat"}}]`
+	want := []Suggestion{
+		{Command: "ls -la", Description: "list files"},
+		{Command: `echo "}{"`, Description: "braces in a string"},
+	}
+
+	tests := []struct {
+		name   string
+		chunks []string
+	}{
+		{name: "one chunk", chunks: []string{answer}},
+		{name: "split inside keys and escapes", chunks: []string{
+			`[{"1": {"ls -`, `la": "list files"`, `}}, {"2": {"echo \`, `"}{\"": "braces in a string"}`, `}, {"3": {"ls -la": "repeat"}}]`,
+		}},
+	}
+	// One byte at a time
+	var bytes []string
+	for i := range answer {
+		bytes = append(bytes, answer[i:i+1])
+	}
+	tests = append(tests, struct {
+		name   string
+		chunks []string
+	}{name: "byte by byte", chunks: bytes})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stream SuggestionStream
+			var got []Suggestion
+			for _, chunk := range tt.chunks {
+				got = append(got, stream.Feed(chunk)...)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Feed() = %#v, want %#v", got, want)
+			}
+			if stream.Emitted() != len(want) {
+				t.Errorf("Emitted() = %d, want %d", stream.Emitted(), len(want))
+			}
+			if stream.Content() != answer {
+				t.Errorf("Content() = %q, want the whole answer", stream.Content())
+			}
+		})
+	}
+}
+
+func TestSuggestionStreamCompletesOnClosingBrace(t *testing.T) {
+	var stream SuggestionStream
+	if got := stream.Feed(`[{"1": {"pwd": "print directory"`); len(got) != 0 {
+		t.Fatalf("Feed() before the closing brace = %#v, want none", got)
+	}
+	got := stream.Feed(`}`)
+	want := []Suggestion{{Command: "pwd", Description: "print directory"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Feed() = %#v, want %#v", got, want)
+	}
+}